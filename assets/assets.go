@@ -0,0 +1,15 @@
+// Package assets 打包进程序中的资源文件
+package assets
+
+import _ "embed"
+
+// NotificationSound 内置的提示音，没有配置提示音文件或文件无法读取时使用，
+// 工作、休息和长休息结束共用这一个提示音
+//
+//go:embed notification.mp3
+var NotificationSound []byte
+
+// TrayIcon 系统托盘图标
+//
+//go:embed icons/tomato.png
+var TrayIcon []byte
@@ -1,33 +1,85 @@
 package main
 
 import (
+	"TodoList/internal/api"
+	"TodoList/internal/audio/device"
 	"TodoList/internal/config"
+	"TodoList/internal/storage"
 	"TodoList/internal/ui"
+	"flag"
 	"fyne.io/fyne/v2/app"
 	"log"
 )
 
 func main() {
+	profile := flag.String("profile", "", "使用独立的数据库文件，例如 work 或 personal")
+	flag.Parse()
+
 	// 初始化配置管理器
 	configManager, err := config.NewManager()
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer configManager.Close()
+
+	// 打开数据库
+	dbPath, err := configManager.DatabasePath(*profile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	db, err := storage.NewDatabase(dbPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	cfg := configManager.GetConfig()
+	db.SetAutoCompleteParent(cfg.Tasks.AutoCompleteParent)
 
 	// 创建应用
 	myApp := app.New()
 
 	// 应用主题设置
-	cfg := configManager.GetConfig()
-	if cfg.Theme.DarkMode {
-		// 设置深色主题
-	}
+	ui.ApplyTheme(myApp, cfg.Theme)
 
 	// 创建主窗口
-	mainWindow := ui.NewMainWindow(myApp, configManager)
+	mainWindow := ui.NewMainWindow(myApp, configManager, db, &device.Speaker{})
 
 	// 设置窗口大小
 	mainWindow.SetSize(float32(cfg.App.WindowWidth), float32(cfg.App.WindowHeight))
 
+	// 启动本地 HTTP 接口
+	if cfg.API.Enabled {
+		if server := startAPIServer(configManager, db, mainWindow); server != nil {
+			defer server.Close()
+		}
+	}
+
 	mainWindow.Show()
 }
+
+// startAPIServer 在后台启动本地接口，失败时只记录日志，不影响界面使用
+func startAPIServer(configManager *config.Manager, db *storage.Database, mainWindow *ui.MainWindow) *api.Server {
+	apiConfig := configManager.GetConfig().API
+	token, err := configManager.EnsureAPIToken()
+	if err != nil {
+		log.Println("生成接口令牌失败:", err)
+		return nil
+	}
+	apiConfig.Token = token
+
+	server, err := api.NewServer(db, mainWindow.Timers(), apiConfig)
+	if err != nil {
+		log.Println("无法启动本地接口:", err)
+		return nil
+	}
+	server.SetOnChange(mainWindow.ReloadTasks)
+
+	go func() {
+		log.Printf("本地接口已在 http://%s 启动", server.Addr())
+		if err := server.ListenAndServe(); err != nil {
+			log.Println("本地接口已停止:", err)
+		}
+	}()
+	return server
+}
@@ -0,0 +1,162 @@
+package api
+
+import (
+	"TodoList/internal/config"
+	"TodoList/internal/models"
+	"TodoList/internal/storage"
+	"TodoList/internal/timer"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// EngineTimers 不依赖图形界面的番茄钟集合，按今天的 timer_configs 按需创建计时引擎
+type EngineTimers struct {
+	mu       sync.Mutex
+	db       *storage.Database
+	defaults config.PomodoroConfig
+	engines  map[string]*timer.Engine // 键为 "日期/名称"
+	saveErrs map[string]error         // 尚未报告给调用方的保存记录失败，键与 engines 相同
+}
+
+func NewEngineTimers(db *storage.Database, defaults config.PomodoroConfig) *EngineTimers {
+	return &EngineTimers{
+		db:       db,
+		defaults: defaults,
+		engines:  make(map[string]*timer.Engine),
+		saveErrs: make(map[string]error),
+	}
+}
+
+func (t *EngineTimers) ListTimers() ([]TimerStatus, error) {
+	today, _ := parseDate("")
+	configs, err := t.db.GetTimerConfigsByDate(today)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	statuses := make([]TimerStatus, 0, len(configs))
+	for _, config := range configs {
+		key := engineKey(today, config.Name)
+		engine, ok := t.engines[key]
+		if !ok {
+			// 尚未启动过的番茄钟显示为空闲状态
+			engine = t.newEngine(config)
+		}
+		status := NewTimerStatus(config.Name, engine, nil)
+		if err := t.saveErrs[key]; err != nil {
+			status.Error = fmt.Sprintf("保存番茄钟记录失败: %v", err)
+			delete(t.saveErrs, key)
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (t *EngineTimers) StartTimer(name string) (TimerStatus, error) {
+	return t.control(name, (*timer.Engine).Start)
+}
+
+func (t *EngineTimers) PauseTimer(name string) (TimerStatus, error) {
+	return t.control(name, (*timer.Engine).Pause)
+}
+
+func (t *EngineTimers) ResetTimer(name string) (TimerStatus, error) {
+	return t.control(name, (*timer.Engine).Reset)
+}
+
+// Close 重置所有计时器，正在进行的工作时段会记录为被打断
+func (t *EngineTimers) Close() {
+	t.mu.Lock()
+	engines := make([]*timer.Engine, 0, len(t.engines))
+	for _, engine := range t.engines {
+		engines = append(engines, engine)
+	}
+	t.mu.Unlock()
+
+	for _, engine := range engines {
+		engine.Reset()
+		engine.Drain()
+	}
+}
+
+// control 执行操作并等待事件处理完，保存记录失败时返回错误
+func (t *EngineTimers) control(name string, action func(*timer.Engine)) (TimerStatus, error) {
+	today, _ := parseDate("")
+	key := engineKey(today, name)
+	engine, err := t.engine(name)
+	if err != nil {
+		return TimerStatus{}, err
+	}
+	action(engine)
+	engine.Drain()
+
+	t.mu.Lock()
+	err = t.saveErrs[key]
+	delete(t.saveErrs, key)
+	t.mu.Unlock()
+	if err != nil {
+		return TimerStatus{}, fmt.Errorf("保存番茄钟记录失败: %w", err)
+	}
+	return NewTimerStatus(name, engine, nil), nil
+}
+
+// engine 返回今天指定名称的计时引擎，第一次使用时创建
+func (t *EngineTimers) engine(name string) (*timer.Engine, error) {
+	today, _ := parseDate("")
+	key := engineKey(today, name)
+
+	t.mu.Lock()
+	engine, ok := t.engines[key]
+	t.mu.Unlock()
+	if ok {
+		return engine, nil
+	}
+
+	config, err := t.db.GetTimerConfigByName(name, today)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTimerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if engine, ok := t.engines[key]; ok {
+		return engine, nil
+	}
+
+	engine = t.newEngine(config)
+	engine.Subscribe(func(ev timer.Event) { t.saveRecord(key, ev) })
+	t.engines[key] = engine
+	return engine, nil
+}
+
+func (t *EngineTimers) newEngine(config *models.TimerConfig) *timer.Engine {
+	return timer.NewEngine(timer.NewConfig(config, t.defaults), nil)
+}
+
+// saveRecord 将完成或被打断的工作时段写入数据库，失败时留给下一次操作或查询报告
+func (t *EngineTimers) saveRecord(key string, ev timer.Event) {
+	record := ev.Record(0)
+	if record == nil {
+		return
+	}
+	if err := t.db.SavePomodoroRecord(record); err != nil {
+		log.Printf("保存番茄钟 %s 的记录失败: %v", key, err)
+		t.mu.Lock()
+		t.saveErrs[key] = err
+		t.mu.Unlock()
+	}
+}
+
+func engineKey(date time.Time, name string) string {
+	return date.Format(dateLayout) + "/" + name
+}
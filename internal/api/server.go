@@ -0,0 +1,173 @@
+// Package api 提供本地 HTTP/JSON 接口，供脚本、编辑器插件等外部工具操作任务和番茄钟
+package api
+
+import (
+	"TodoList/internal/config"
+	"TodoList/internal/storage"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// Server 基于 storage.Database 的 HTTP 接口服务
+type Server struct {
+	db       *storage.Database
+	timers   Timers
+	token    string
+	server   *http.Server
+	onChange func() // 任务或番茄钟配置被接口修改后的回调
+}
+
+// NewServer 创建接口服务，默认只允许监听本机地址，并且必须设置访问令牌
+func NewServer(db *storage.Database, timers Timers, cfg config.APIConfig) (*Server, error) {
+	if cfg.Token == "" {
+		return nil, errors.New("api.token 不能为空")
+	}
+	if !cfg.AllowRemote && !isLoopback(cfg.Address) {
+		return nil, fmt.Errorf("api.address %q 不是本机地址，如需远程访问请设置 api.allow_remote", cfg.Address)
+	}
+
+	s := &Server{
+		db:     db,
+		timers: timers,
+		token:  cfg.Token,
+	}
+	s.server = &http.Server{
+		Addr:              cfg.Address,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	return s, nil
+}
+
+// Handler 返回带令牌校验的路由
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/tasks", s.listTasks)
+	mux.HandleFunc("POST /api/tasks", s.createTask)
+	mux.HandleFunc("GET /api/tasks/{id}", s.getTask)
+	mux.HandleFunc("PUT /api/tasks/{id}", s.updateTask)
+	mux.HandleFunc("DELETE /api/tasks/{id}", s.deleteTask)
+
+	mux.HandleFunc("GET /api/timer-configs", s.listTimerConfigs)
+	mux.HandleFunc("POST /api/timer-configs", s.createTimerConfig)
+
+	mux.HandleFunc("GET /api/timers", s.listTimers)
+	mux.HandleFunc("POST /api/timers/{name}/{action}", s.controlTimer)
+
+	mux.HandleFunc("GET /api/stats/tasks", s.taskStats)
+	mux.HandleFunc("GET /api/stats/pomodoros", s.pomodoroStats)
+	mux.HandleFunc("GET /api/stats/tags", s.tagStats)
+
+	return s.authenticate(mux)
+}
+
+// SetOnChange 设置数据被接口修改后的回调，界面可以借此刷新
+func (s *Server) SetOnChange(callback func()) {
+	s.onChange = callback
+}
+
+// Addr 返回监听地址
+func (s *Server) Addr() string {
+	return s.server.Addr
+}
+
+// ListenAndServe 开始监听，服务关闭后返回 nil
+func (s *Server) ListenAndServe() error {
+	err := s.server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown 等待正在处理的请求结束后关闭服务
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+// Close 立即关闭服务
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+// authenticate 校验 Authorization: Bearer <token> 或 X-API-Token 请求头
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("X-API-Token")
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			token = strings.TrimPrefix(auth, "Bearer ")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, "无效的访问令牌")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) changed() {
+	if s.onChange != nil {
+		s.onChange()
+	}
+}
+
+// isLoopback 判断监听地址是否只允许本机访问
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Println("写入接口响应失败:", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// decodeJSON 解析请求体，不允许出现未知字段
+func decodeJSON(r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("无效的请求内容: %v", err)
+	}
+	return nil
+}
+
+// parseDate 解析日期参数，为空时返回今天
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()), nil
+	}
+	date, err := time.ParseInLocation(dateLayout, s, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("无效的日期 %q，格式应为 2006-01-02", s)
+	}
+	return date, nil
+}
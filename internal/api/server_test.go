@@ -0,0 +1,142 @@
+package api
+
+import (
+	"TodoList/internal/config"
+	"TodoList/internal/storage"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+const testToken = "secret"
+
+// newTestServer 创建使用临时数据库和不依赖界面的番茄钟的接口服务
+func newTestServer(t *testing.T) (*Server, *storage.Database) {
+	t.Helper()
+	return newTestServerAt(t, filepath.Join(t.TempDir(), "test.db"))
+}
+
+// newTestServerAt 创建使用 path 处数据库的接口服务
+func newTestServerAt(t *testing.T, path string) (*Server, *storage.Database) {
+	t.Helper()
+	db, err := storage.NewDatabase(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	timers := NewEngineTimers(db, config.DefaultConfig().Pomodoro)
+	t.Cleanup(timers.Close)
+	s, err := NewServer(db, timers, config.APIConfig{Address: "127.0.0.1:0", Token: testToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, db
+}
+
+// do 带着访问令牌发送请求，body 不为 nil 时编码为 JSON
+func do(t *testing.T, s *Server, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, req)
+	return rec
+}
+
+// decode 检查状态码并解析响应
+func decode(t *testing.T, rec *httptest.ResponseRecorder, status int, v any) {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("status = %d, want %d, body %s", rec.Code, status, rec.Body)
+	}
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("decode %s: %v", rec.Body, err)
+		}
+	}
+}
+
+func TestNewServerChecksConfig(t *testing.T) {
+	tests := []struct {
+		cfg     config.APIConfig
+		wantErr bool
+	}{
+		{config.APIConfig{Address: "127.0.0.1:8080"}, true}, // 没有令牌
+		{config.APIConfig{Address: "127.0.0.1:8080", Token: testToken}, false},
+		{config.APIConfig{Address: "localhost:8080", Token: testToken}, false},
+		{config.APIConfig{Address: "0.0.0.0:8080", Token: testToken}, true},
+		{config.APIConfig{Address: ":8080", Token: testToken}, true},
+		{config.APIConfig{Address: "0.0.0.0:8080", Token: testToken, AllowRemote: true}, false},
+	}
+	for _, tt := range tests {
+		_, err := NewServer(nil, nil, tt.cfg)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("NewServer(%+v) err = %v, want error %v", tt.cfg, err, tt.wantErr)
+		}
+	}
+}
+
+func TestIsLoopback(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"127.0.0.1:8080", true},
+		{"127.1.2.3:8080", true},
+		{"[::1]:8080", true},
+		{"localhost:8080", true},
+		{"0.0.0.0:8080", false},
+		{":8080", false},
+		{"192.168.1.2:8080", false},
+		{"example.com:8080", false},
+		{"127.0.0.1", false}, // 缺少端口
+	}
+	for _, tt := range tests {
+		if got := isLoopback(tt.addr); got != tt.want {
+			t.Errorf("isLoopback(%q) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	s, _ := newTestServer(t)
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		status int
+	}{
+		{"missing", "", "", http.StatusUnauthorized},
+		{"wrong bearer", "Authorization", "Bearer wrong", http.StatusUnauthorized},
+		{"not bearer", "Authorization", testToken, http.StatusUnauthorized},
+		{"bearer", "Authorization", "Bearer " + testToken, http.StatusOK},
+		{"header", "X-API-Token", testToken, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/tasks", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			s.Handler().ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.status == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") != "Bearer" {
+				t.Fatalf("WWW-Authenticate = %q, want Bearer", rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
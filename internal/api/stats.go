@@ -0,0 +1,69 @@
+package api
+
+import (
+	"TodoList/internal/models"
+	"net/http"
+	"time"
+)
+
+func (s *Server) taskStats(w http.ResponseWriter, r *http.Request) {
+	startDate, endDate, err := statsRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stats, err := s.db.GetTaskStats(startDate, endDate)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (s *Server) pomodoroStats(w http.ResponseWriter, r *http.Request) {
+	startDate, endDate, err := statsRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stats, err := s.db.GetPomodoroStats(startDate, endDate)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (s *Server) tagStats(w http.ResponseWriter, r *http.Request) {
+	startDate, endDate, err := statsRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	stats, err := s.db.GetTagStats(startDate, endDate)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if stats == nil {
+		stats = []models.TagStat{}
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// statsRange 解析 from 和 to 参数，默认统计今天，结束日期包含当天
+func statsRange(r *http.Request) (time.Time, time.Time, error) {
+	query := r.URL.Query()
+	startDate, err := parseDate(query.Get("from"))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	endDate, err := parseDate(query.Get("to"))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return startDate, endDate.AddDate(0, 0, 1).Add(-time.Nanosecond), nil
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"TodoList/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// taskRequest 创建或修改任务的请求，修改时只更新提供的字段
+type taskRequest struct {
+	Title       *string   `json:"title"`
+	Description *string   `json:"description"`
+	Status      *string   `json:"status"`
+	Priority    *int      `json:"priority"`
+	Date        *string   `json:"date"`
+	DueAt       *string   `json:"due_at"`     // RFC 3339 格式，空字符串表示清除截止时间
+	ParentID    *int64    `json:"parent_id"`  // 0 表示取消父任务
+	Tags        *[]string `json:"tags"`       // 替换全部标签
+	Recurrence  *string   `json:"recurrence"` // 重复规则，如 FREQ=DAILY，修改时作用于以后所有的重复，空字符串表示停止重复
+}
+
+func (s *Server) listTasks(w http.ResponseWriter, r *http.Request) {
+	date, err := parseDate(r.URL.Query().Get("date"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.db.MaterializeRecurrences(date.Format(dateLayout)); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	tasks, err := s.db.GetTasksByDate(date.Format(dateLayout))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if tasks == nil {
+		tasks = []*models.Task{}
+	}
+	writeJSON(w, http.StatusOK, tasks)
+}
+
+func (s *Server) createTask(w http.ResponseWriter, r *http.Request) {
+	var req taskRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Title == nil || strings.TrimSpace(*req.Title) == "" {
+		writeError(w, http.StatusBadRequest, "title 不能为空")
+		return
+	}
+
+	task := &models.Task{
+		Status:    models.StatusTodo,
+		CreatedAt: time.Now(),
+		Priority:  1,
+		Date:      time.Now().Format(dateLayout),
+	}
+	if err := req.apply(task); err != nil {
+		writeError(w, taskErrorStatus(err, http.StatusBadRequest), err.Error())
+		return
+	}
+	rule, err := req.recurrenceRule()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := s.db.SaveRecurringTask(task, rule, false); err != nil {
+		writeError(w, taskErrorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+	s.changed()
+	writeJSON(w, http.StatusCreated, task)
+}
+
+func (s *Server) getTask(w http.ResponseWriter, r *http.Request) {
+	task, ok := s.findTask(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, task)
+}
+
+func (s *Server) updateTask(w http.ResponseWriter, r *http.Request) {
+	task, ok := s.findTask(w, r)
+	if !ok {
+		return
+	}
+
+	var req taskRequest
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Title != nil && strings.TrimSpace(*req.Title) == "" {
+		writeError(w, http.StatusBadRequest, "title 不能为空")
+		return
+	}
+	if err := req.apply(task); err != nil {
+		writeError(w, taskErrorStatus(err, http.StatusBadRequest), err.Error())
+		return
+	}
+	rule, err := req.recurrenceRule()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// 提供了重复规则时作用于以后所有的重复
+	if err := s.db.SaveRecurringTask(task, rule, req.Recurrence != nil); err != nil {
+		writeError(w, taskErrorStatus(err, http.StatusInternalServerError), err.Error())
+		return
+	}
+	s.changed()
+	writeJSON(w, http.StatusOK, task)
+}
+
+func (s *Server) deleteTask(w http.ResponseWriter, r *http.Request) {
+	task, ok := s.findTask(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.db.DeleteTask(task.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.changed()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findTask 根据路径中的 ID 查找任务，失败时直接写入错误响应
+func (s *Server) findTask(w http.ResponseWriter, r *http.Request) (*models.Task, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("无效的任务 ID: %s", r.PathValue("id")))
+		return nil, false
+	}
+
+	task, err := s.db.GetTaskByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("任务 #%d 不存在", id))
+		return nil, false
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+	return task, true
+}
+
+// apply 将请求中提供的字段写入任务
+func (req *taskRequest) apply(task *models.Task) error {
+	if req.Title != nil {
+		task.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.Description != nil {
+		task.Description = *req.Description
+	}
+	if req.Priority != nil {
+		task.Priority = *req.Priority
+	}
+	if req.Date != nil {
+		date, err := parseDate(*req.Date)
+		if err != nil {
+			return err
+		}
+		task.Date = date.Format(dateLayout)
+	}
+	if req.DueAt != nil {
+		task.DueAt = nil
+		if *req.DueAt != "" {
+			dueAt, err := time.Parse(time.RFC3339, *req.DueAt)
+			if err != nil {
+				return fmt.Errorf("无效的截止时间 %q，格式应为 RFC 3339", *req.DueAt)
+			}
+			task.DueAt = &dueAt
+		}
+	}
+	if req.Tags != nil {
+		task.Tags = models.NormalizeTags(*req.Tags)
+	}
+	if req.ParentID != nil {
+		task.ParentID = nil
+		if *req.ParentID != 0 {
+			parentID := *req.ParentID
+			task.ParentID = &parentID
+		}
+	}
+	if req.Status != nil {
+		status, err := models.ParseTaskStatus(*req.Status)
+		if err != nil {
+			return err
+		}
+		if err := task.SetStatus(status, time.Now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recurrenceRule 解析请求中的重复规则，没有提供或为空字符串时返回 nil
+func (req *taskRequest) recurrenceRule() (*models.RecurrenceRule, error) {
+	if req.Recurrence == nil || strings.TrimSpace(*req.Recurrence) == "" {
+		return nil, nil
+	}
+	return models.ParseRecurrenceRule(*req.Recurrence)
+}
+
+// taskErrorStatus 根据错误类型选择响应状态码，其他错误使用 fallback
+func taskErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, models.ErrInvalidTransition):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrInvalidStatus), errors.Is(err, models.ErrInvalidParent),
+		errors.Is(err, models.ErrInvalidRecurrence):
+		return http.StatusBadRequest
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	}
+	return fallback
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"TodoList/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTaskCRUD(t *testing.T) {
+	s, _ := newTestServer(t)
+
+	var created models.Task
+	decode(t, do(t, s, http.MethodPost, "/api/tasks", map[string]any{
+		"title": "  写周报 ", "priority": 3, "date": "2024-01-01", "tags": []string{"工作"},
+	}), http.StatusCreated, &created)
+	if created.ID == 0 || created.Title != "写周报" || created.Status != models.StatusTodo ||
+		created.Date != "2024-01-01" || created.Priority != 3 || len(created.Tags) != 1 {
+		t.Fatalf("created = %+v", created)
+	}
+	path := "/api/tasks/" + strconv.FormatInt(created.ID, 10)
+
+	var listed []models.Task
+	decode(t, do(t, s, http.MethodGet, "/api/tasks?date=2024-01-01", nil), http.StatusOK, &listed)
+	if len(listed) != 1 || listed[0].ID != created.ID {
+		t.Fatalf("listed = %+v", listed)
+	}
+
+	// 修改时只更新提供的字段
+	var updated models.Task
+	decode(t, do(t, s, http.MethodPut, path, map[string]any{"status": "done"}), http.StatusOK, &updated)
+	if updated.Status != models.StatusDone || updated.CompletedAt == nil || updated.Title != "写周报" {
+		t.Fatalf("updated = %+v", updated)
+	}
+	var fetched models.Task
+	decode(t, do(t, s, http.MethodGet, path, nil), http.StatusOK, &fetched)
+	if fetched.Status != models.StatusDone {
+		t.Fatalf("fetched = %+v", fetched)
+	}
+
+	decode(t, do(t, s, http.MethodDelete, path, nil), http.StatusNoContent, nil)
+	decode(t, do(t, s, http.MethodGet, path, nil), http.StatusNotFound, nil)
+
+	// 空日期返回空数组而不是 null
+	rec := do(t, s, http.MethodGet, "/api/tasks?date=2024-01-01", nil)
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Fatalf("empty list = %d %s", rec.Code, rec.Body)
+	}
+}
+
+func TestTaskErrors(t *testing.T) {
+	s, _ := newTestServer(t)
+	var task models.Task
+	decode(t, do(t, s, http.MethodPost, "/api/tasks", map[string]any{"title": "写周报", "status": "cancelled"}), http.StatusCreated, &task)
+	path := "/api/tasks/" + strconv.FormatInt(task.ID, 10)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+		status int
+	}{
+		{"missing title", http.MethodPost, "/api/tasks", map[string]any{"priority": 1}, http.StatusBadRequest},
+		{"blank title", http.MethodPut, path, map[string]any{"title": " "}, http.StatusBadRequest},
+		{"unknown field", http.MethodPost, "/api/tasks", map[string]any{"title": "a", "owner": "me"}, http.StatusBadRequest},
+		{"invalid date", http.MethodPost, "/api/tasks", map[string]any{"title": "a", "date": "01/02/2024"}, http.StatusBadRequest},
+		{"invalid due", http.MethodPost, "/api/tasks", map[string]any{"title": "a", "due_at": "tomorrow"}, http.StatusBadRequest},
+		{"invalid status", http.MethodPut, path, map[string]any{"status": "later"}, http.StatusBadRequest},
+		{"invalid transition", http.MethodPut, path, map[string]any{"status": "done"}, http.StatusConflict},
+		{"missing parent", http.MethodPost, "/api/tasks", map[string]any{"title": "a", "parent_id": 999}, http.StatusBadRequest},
+		{"invalid id", http.MethodGet, "/api/tasks/abc", nil, http.StatusBadRequest},
+		{"missing task", http.MethodDelete, "/api/tasks/999", nil, http.StatusNotFound},
+		{"invalid list date", http.MethodGet, "/api/tasks?date=today", nil, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body map[string]string
+			decode(t, do(t, s, tt.method, tt.path, tt.body), tt.status, &body)
+			if body["error"] == "" {
+				t.Fatalf("body = %v, want an error message", body)
+			}
+		})
+	}
+}
+
+func TestTaskRecurrence(t *testing.T) {
+	s, db := newTestServer(t)
+
+	// 无效的规则不会留下任务
+	for _, rule := range []string{"FREQ=HOURLY", "FREQ=WEEKLY;FROM=COMPLETION", "INTERVAL=2"} {
+		var body map[string]string
+		decode(t, do(t, s, http.MethodPost, "/api/tasks", map[string]any{
+			"title": "浇花", "date": "2024-01-01", "recurrence": rule,
+		}), http.StatusBadRequest, &body)
+		if body["error"] == "" {
+			t.Fatalf("%s: body = %v", rule, body)
+		}
+	}
+	if tasks, err := db.GetTasksByDate("2024-01-01"); err != nil || len(tasks) != 0 {
+		t.Fatalf("tasks = %d, %v, want 0", len(tasks), err)
+	}
+
+	var task models.Task
+	decode(t, do(t, s, http.MethodPost, "/api/tasks", map[string]any{
+		"title": "浇花", "date": "2024-01-01", "recurrence": "FREQ=DAILY",
+	}), http.StatusCreated, &task)
+	if task.RecurrenceID == nil || task.Occurrence != "2024-01-01" {
+		t.Fatalf("task = %+v, want the first occurrence", task)
+	}
+
+	var next []models.Task
+	decode(t, do(t, s, http.MethodGet, "/api/tasks?date=2024-01-02", nil), http.StatusOK, &next)
+	if len(next) != 1 || next[0].Title != "浇花" || *next[0].RecurrenceID != *task.RecurrenceID {
+		t.Fatalf("next day = %+v", next)
+	}
+
+	// 空字符串停止重复
+	var stopped models.Task
+	decode(t, do(t, s, http.MethodPut, "/api/tasks/"+strconv.FormatInt(next[0].ID, 10), map[string]any{"recurrence": ""}), http.StatusOK, &stopped)
+	if stopped.RecurrenceID != nil {
+		t.Fatalf("stopped = %+v, want no series", stopped)
+	}
+	decode(t, do(t, s, http.MethodGet, "/api/tasks?date=2024-01-03", nil), http.StatusOK, &next)
+	if len(next) != 0 {
+		t.Fatalf("after stop = %+v, want none", next)
+	}
+}
+
+func TestTaskDefaultsToToday(t *testing.T) {
+	s, _ := newTestServer(t)
+	var task models.Task
+	decode(t, do(t, s, http.MethodPost, "/api/tasks", map[string]any{"title": "写周报"}), http.StatusCreated, &task)
+	if today := time.Now().Format(dateLayout); task.Date != today || task.Priority != 1 {
+		t.Fatalf("task = %+v, want today with priority 1", task)
+	}
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"TodoList/internal/models"
+	"TodoList/internal/timer"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrTimerNotFound 指定名称的番茄钟不存在
+var ErrTimerNotFound = errors.New("番茄钟不存在")
+
+// Timers 接口可以控制的番茄钟集合，图形界面和命令行各自提供实现
+type Timers interface {
+	ListTimers() ([]TimerStatus, error)
+	StartTimer(name string) (TimerStatus, error)
+	PauseTimer(name string) (TimerStatus, error)
+	ResetTimer(name string) (TimerStatus, error)
+}
+
+// TimerStatus 番茄钟的当前状态，时长以秒为单位
+type TimerStatus struct {
+	Name      string `json:"name"`
+	Phase     string `json:"phase"`
+	State     string `json:"state"` // idle、running 或 paused
+	Remaining int64  `json:"remaining"`
+	Duration  int64  `json:"duration"`
+	Completed int    `json:"completed"`
+	TaskID    int64  `json:"task_id,omitempty"`
+	Error     string `json:"error,omitempty"` // 上一次保存番茄钟记录失败的原因
+}
+
+var stateNames = map[models.TimerState]string{
+	models.StateIdle:    "idle",
+	models.StateRunning: "running",
+	models.StatePaused:  "paused",
+}
+
+// NewTimerStatus 根据计时引擎生成状态，task 可以为 nil
+func NewTimerStatus(name string, engine *timer.Engine, task *models.Task) TimerStatus {
+	snapshot := engine.Snapshot()
+	status := TimerStatus{
+		Name:      name,
+		Phase:     engine.Phase().String(),
+		State:     stateNames[snapshot.State],
+		Remaining: int64(snapshot.Remaining.Seconds()),
+		Duration:  int64(snapshot.Duration.Seconds()),
+		Completed: engine.Completed(),
+	}
+	if task != nil {
+		status.TaskID = task.ID
+	}
+	return status
+}
+
+// timerConfigJSON 番茄钟配置，时长使用 "25m" 这样的格式
+// 自动开始和长休息间隔省略时使用全局配置
+type timerConfigJSON struct {
+	ID                int64  `json:"id,omitempty"`
+	Name              string `json:"name"`
+	Work              string `json:"work"`
+	ShortBreak        string `json:"short_break"`
+	LongBreak         string `json:"long_break"`
+	Date              string `json:"date"`
+	AutoStartBreak    *bool  `json:"auto_start_break,omitempty"`
+	AutoStartPomodoro *bool  `json:"auto_start_pomodoro,omitempty"`
+	LongBreakAfter    *int   `json:"long_break_after,omitempty"`
+}
+
+func (s *Server) listTimerConfigs(w http.ResponseWriter, r *http.Request) {
+	date, err := parseDate(r.URL.Query().Get("date"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	configs, err := s.db.GetTimerConfigsByDate(date)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	result := make([]timerConfigJSON, 0, len(configs))
+	for _, c := range configs {
+		result = append(result, timerConfigJSON{
+			ID:                c.ID,
+			Name:              c.Name,
+			Work:              c.WorkDuration.String(),
+			ShortBreak:        c.BreakDuration.String(),
+			LongBreak:         c.LongBreak.String(),
+			Date:              c.Date.Format(dateLayout),
+			AutoStartBreak:    c.AutoStartBreak,
+			AutoStartPomodoro: c.AutoStartPomodoro,
+			LongBreakAfter:    c.LongBreakAfter,
+		})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) createTimerConfig(w http.ResponseWriter, r *http.Request) {
+	var req timerConfigJSON
+	if err := decodeJSON(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	config, err := req.timerConfig()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	_, err = s.db.GetTimerConfigByName(config.Name, config.Date)
+	if err == nil {
+		writeError(w, http.StatusConflict, fmt.Sprintf("%s 已经有名为 %q 的番茄钟", config.Date.Format(dateLayout), config.Name))
+		return
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err := s.db.SaveTimerConfig(config); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.changed()
+
+	req.ID = config.ID
+	req.Date = config.Date.Format(dateLayout)
+	writeJSON(w, http.StatusCreated, req)
+}
+
+// timerConfig 校验请求并转换为 models.TimerConfig
+func (req *timerConfigJSON) timerConfig() (*models.TimerConfig, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("name 不能为空")
+	}
+
+	date, err := parseDate(req.Date)
+	if err != nil {
+		return nil, err
+	}
+
+	durations := make([]time.Duration, 3)
+	for i, field := range []struct{ key, value string }{
+		{"work", req.Work},
+		{"short_break", req.ShortBreak},
+		{"long_break", req.LongBreak},
+	} {
+		d, err := time.ParseDuration(field.value)
+		if err != nil || d < time.Second {
+			return nil, fmt.Errorf("%s 必须是有效的时长，例如 25m，当前为 %q", field.key, field.value)
+		}
+		durations[i] = d
+	}
+	if req.LongBreakAfter != nil && *req.LongBreakAfter < 1 {
+		return nil, fmt.Errorf("long_break_after 必须至少为 1，当前为 %d", *req.LongBreakAfter)
+	}
+
+	return &models.TimerConfig{
+		Name:              name,
+		WorkDuration:      durations[0],
+		BreakDuration:     durations[1],
+		LongBreak:         durations[2],
+		Date:              date,
+		AutoStartBreak:    req.AutoStartBreak,
+		AutoStartPomodoro: req.AutoStartPomodoro,
+		LongBreakAfter:    req.LongBreakAfter,
+	}, nil
+}
+
+func (s *Server) listTimers(w http.ResponseWriter, r *http.Request) {
+	timers, err := s.timers.ListTimers()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if timers == nil {
+		timers = []TimerStatus{}
+	}
+	writeJSON(w, http.StatusOK, timers)
+}
+
+func (s *Server) controlTimer(w http.ResponseWriter, r *http.Request) {
+	var control func(string) (TimerStatus, error)
+	switch r.PathValue("action") {
+	case "start":
+		control = s.timers.StartTimer
+	case "pause":
+		control = s.timers.PauseTimer
+	case "reset":
+		control = s.timers.ResetTimer
+	default:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("未知的操作: %s", r.PathValue("action")))
+		return
+	}
+
+	name := r.PathValue("name")
+	status, err := control(name)
+	if errors.Is(err, ErrTimerNotFound) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("今天没有名为 %q 的番茄钟", name))
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countRecords 通过单独的连接读取 path 处数据库中的番茄钟记录数
+func countRecords(t *testing.T, path string, interrupted bool) int {
+	t.Helper()
+	conn := openRaw(t, path)
+	var n int
+	err := conn.QueryRow(`SELECT COUNT(*) FROM pomodoro_records WHERE interrupted = ?`, interrupted).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func openRaw(t *testing.T, path string) *sql.DB {
+	t.Helper()
+	conn, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// createTestTimer 通过接口创建今天的番茄钟配置
+func createTestTimer(t *testing.T, s *Server, name string) {
+	t.Helper()
+	var created timerConfigJSON
+	decode(t, do(t, s, http.MethodPost, "/api/timer-configs", map[string]any{
+		"name": name, "work": "25m", "short_break": "5m", "long_break": "15m",
+	}), http.StatusCreated, &created)
+	if created.ID == 0 || created.Date != time.Now().Format(dateLayout) {
+		t.Fatalf("created = %+v", created)
+	}
+}
+
+func TestTimerControl(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	s, _ := newTestServerAt(t, path)
+	createTestTimer(t, s, "专注")
+
+	var timers []TimerStatus
+	decode(t, do(t, s, http.MethodGet, "/api/timers", nil), http.StatusOK, &timers)
+	if len(timers) != 1 || timers[0].Name != "专注" || timers[0].State != "idle" || timers[0].Remaining != 1500 {
+		t.Fatalf("timers = %+v", timers)
+	}
+
+	for _, step := range []struct{ action, state string }{
+		{"start", "running"},
+		{"pause", "paused"},
+		{"start", "running"},
+		{"reset", "idle"},
+	} {
+		var status TimerStatus
+		decode(t, do(t, s, http.MethodPost, "/api/timers/专注/"+step.action, nil), http.StatusOK, &status)
+		if status.Name != "专注" || status.State != step.state {
+			t.Fatalf("%s: status = %+v, want %s", step.action, status, step.state)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 重置运行中的工作时段记录为被打断
+	if got := countRecords(t, path, true); got != 1 {
+		t.Fatalf("interrupted records = %d, want 1", got)
+	}
+
+	decode(t, do(t, s, http.MethodPost, "/api/timers/休息/start", nil), http.StatusNotFound, nil)
+	decode(t, do(t, s, http.MethodPost, "/api/timers/专注/stop", nil), http.StatusNotFound, nil)
+}
+
+func TestTimerConfigErrors(t *testing.T) {
+	s, _ := newTestServer(t)
+	createTestTimer(t, s, "专注")
+
+	decode(t, do(t, s, http.MethodPost, "/api/timer-configs", map[string]any{
+		"name": "专注", "work": "25m", "short_break": "5m", "long_break": "15m",
+	}), http.StatusConflict, nil)
+	for _, body := range []map[string]any{
+		{"name": "", "work": "25m", "short_break": "5m", "long_break": "15m"},
+		{"name": "a", "work": "25", "short_break": "5m", "long_break": "15m"},
+		{"name": "a", "work": "25m", "short_break": "5m", "long_break": "15m", "long_break_after": 0},
+	} {
+		decode(t, do(t, s, http.MethodPost, "/api/timer-configs", body), http.StatusBadRequest, nil)
+	}
+}
+
+func TestTimerSaveErrorReachesCaller(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	s, _ := newTestServerAt(t, path)
+	createTestTimer(t, s, "专注")
+
+	// 保存番茄钟记录失败时操作返回错误，而不是只打印日志
+	_, err := openRaw(t, path).Exec(`
+        CREATE TRIGGER fail_record BEFORE INSERT ON pomodoro_records
+        BEGIN SELECT RAISE(ABORT, 'disk full'); END
+    `)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decode(t, do(t, s, http.MethodPost, "/api/timers/专注/start", nil), http.StatusOK, nil)
+	time.Sleep(10 * time.Millisecond)
+	var body map[string]string
+	decode(t, do(t, s, http.MethodPost, "/api/timers/专注/reset", nil), http.StatusInternalServerError, &body)
+	if !strings.Contains(body["error"], "disk full") {
+		t.Fatalf("body = %v, want the save error", body)
+	}
+
+	// 错误只报告一次
+	decode(t, do(t, s, http.MethodPost, "/api/timers/专注/reset", nil), http.StatusOK, nil)
+}
@@ -0,0 +1,186 @@
+// Package audio 解码并播放提示音，支持 wav、mp3 和 ogg 文件
+// 实际的播放设备由 Sink 提供，没有可用的音频设备时提示音会被静默忽略
+package audio
+
+import (
+	"TodoList/assets"
+	"TodoList/internal/config"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/effects"
+	"github.com/faiface/beep/mp3"
+	"github.com/faiface/beep/vorbis"
+	"github.com/faiface/beep/wav"
+)
+
+// Sound 表示需要播放提示音的事件
+type Sound string
+
+const (
+	SoundWorkComplete      Sound = "work_complete"
+	SoundBreakComplete     Sound = "break_complete"
+	SoundLongBreakComplete Sound = "long_break_complete"
+)
+
+// Sink 播放解码后的音频，Play 不应等待播放结束
+type Sink interface {
+	Play(s beep.Streamer, format beep.Format) error
+}
+
+// Player 根据配置为每种事件播放对应的提示音，可以在任意协程中调用
+type Player struct {
+	mu      sync.Mutex
+	sink    Sink
+	enabled bool
+	sounds  map[Sound]config.SoundConfig
+	buffers map[string]*beep.Buffer // 按文件路径缓存解码结果，解码失败时为 nil
+	sinkErr error                   // 播放设备第一次出错的原因，之后不再尝试播放
+}
+
+// NewPlayer 创建播放器，sink 为 nil 时不会发出声音
+func NewPlayer(sink Sink) *Player {
+	if sink == nil {
+		sink = &NullSink{}
+	}
+	return &Player{
+		sink:    sink,
+		enabled: true,
+		sounds:  make(map[Sound]config.SoundConfig),
+		buffers: make(map[string]*beep.Buffer),
+	}
+}
+
+// Apply 应用提示音配置，sounds 中的文件应当已经是绝对路径
+func (p *Player) Apply(enabled bool, sounds config.SoundsConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.enabled = enabled
+	p.sounds = map[Sound]config.SoundConfig{
+		SoundWorkComplete:      sounds.WorkComplete,
+		SoundBreakComplete:     sounds.BreakComplete,
+		SoundLongBreakComplete: sounds.LongBreakComplete,
+	}
+}
+
+// Play 播放指定事件的提示音，文件无法读取时改用内置提示音，所有事件共用同一个内置提示音
+func (p *Player) Play(sound Sound) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.enabled || p.sinkErr != nil {
+		return
+	}
+
+	cfg, ok := p.sounds[sound]
+	if !ok {
+		cfg = config.SoundConfig{Volume: 1}
+	}
+	if cfg.Volume <= 0 {
+		return
+	}
+
+	buffer := p.buffer(cfg.File)
+	if buffer == nil {
+		buffer = p.buffer("")
+	}
+	if buffer == nil {
+		return
+	}
+
+	// 音量按线性比例换算为以 2 为底的增益
+	streamer := &effects.Volume{
+		Streamer: buffer.Streamer(0, buffer.Len()),
+		Base:     2,
+		Volume:   math.Log2(math.Min(cfg.Volume, 1)),
+	}
+	if err := p.sink.Play(streamer, buffer.Format()); err != nil {
+		log.Println("无法播放提示音，之后不再尝试:", err)
+		p.sinkErr = err
+	}
+}
+
+// buffer 返回解码后的音频，path 为空时使用内置提示音，调用时必须持有锁
+func (p *Player) buffer(path string) *beep.Buffer {
+	if buffer, ok := p.buffers[path]; ok {
+		return buffer
+	}
+
+	buffer, err := load(path)
+	if err != nil {
+		log.Printf("无法读取提示音 %s: %v", path, err)
+	}
+	p.buffers[path] = buffer
+	return buffer
+}
+
+// load 读取并解码整个音频文件
+func load(path string) (*beep.Buffer, error) {
+	data := assets.NotificationSound
+	format := ".mp3"
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, err
+		}
+		format = strings.ToLower(filepath.Ext(path))
+	}
+
+	streamer, f, err := Decode(bytes.NewReader(data), format)
+	if err != nil {
+		return nil, err
+	}
+	defer streamer.Close()
+
+	buffer := beep.NewBuffer(f)
+	buffer.Append(streamer)
+	if err := streamer.Err(); err != nil {
+		return nil, err
+	}
+	return buffer, nil
+}
+
+// Decode 按扩展名解码音频，format 为 ".wav"、".mp3" 或 ".ogg"
+func Decode(r io.Reader, format string) (beep.StreamSeekCloser, beep.Format, error) {
+	rc, ok := r.(io.ReadCloser)
+	if !ok {
+		rc = io.NopCloser(r)
+	}
+
+	switch format {
+	case ".wav":
+		return wav.Decode(rc)
+	case ".mp3":
+		return mp3.Decode(rc)
+	case ".ogg":
+		return vorbis.Decode(rc)
+	}
+	return nil, beep.Format{}, fmt.Errorf("不支持的音频格式: %q", format)
+}
+
+// NullSink 丢弃所有音频，用于没有音频设备的环境，记录播放次数
+type NullSink struct {
+	mu    sync.Mutex
+	plays int
+}
+
+func (s *NullSink) Play(beep.Streamer, beep.Format) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.plays++
+	return nil
+}
+
+// Plays 返回收到的播放请求数量
+func (s *NullSink) Plays() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.plays
+}
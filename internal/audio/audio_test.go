@@ -0,0 +1,147 @@
+package audio
+
+import (
+	"TodoList/assets"
+	"TodoList/internal/config"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/wav"
+)
+
+// recordingSink 记录每次播放的音频格式，err 不为空时返回该错误
+type recordingSink struct {
+	mu      sync.Mutex
+	formats []beep.Format
+	err     error
+}
+
+func (s *recordingSink) Play(_ beep.Streamer, format beep.Format) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.formats = append(s.formats, format)
+	return s.err
+}
+
+func (s *recordingSink) calls() []beep.Format {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]beep.Format(nil), s.formats...)
+}
+
+func allSounds(sound config.SoundConfig) config.SoundsConfig {
+	return config.SoundsConfig{WorkComplete: sound, BreakComplete: sound, LongBreakComplete: sound}
+}
+
+// embeddedFormat 返回内置提示音的格式
+func embeddedFormat(t *testing.T) beep.Format {
+	t.Helper()
+	streamer, format, err := Decode(bytes.NewReader(assets.NotificationSound), ".mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamer.Close()
+	return format
+}
+
+// writeWav 在临时目录写入一段采样率为 rate 的静音 wav 文件
+func writeWav(t *testing.T, rate beep.SampleRate) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "custom.wav")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	format := beep.Format{SampleRate: rate, NumChannels: 1, Precision: 2}
+	if err := wav.Encode(f, beep.Take(rate.N(time.Second/10), beep.Silence(-1)), format); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPlayDisabled(t *testing.T) {
+	sink := &NullSink{}
+	p := NewPlayer(sink)
+	p.Apply(false, allSounds(config.SoundConfig{Volume: 1}))
+
+	p.Play(SoundWorkComplete)
+	p.Play(SoundBreakComplete)
+	if got := sink.Plays(); got != 0 {
+		t.Fatalf("plays = %d, want 0 when notification sound is off", got)
+	}
+}
+
+func TestPlayMutedVolume(t *testing.T) {
+	sink := &NullSink{}
+	p := NewPlayer(sink)
+	sounds := allSounds(config.SoundConfig{Volume: 1})
+	sounds.BreakComplete.Volume = 0
+	p.Apply(true, sounds)
+
+	p.Play(SoundBreakComplete)
+	if got := sink.Plays(); got != 0 {
+		t.Fatalf("plays = %d, want 0 for volume 0", got)
+	}
+	p.Play(SoundWorkComplete)
+	if got := sink.Plays(); got != 1 {
+		t.Fatalf("plays = %d, want 1", got)
+	}
+}
+
+func TestPlayCustomFile(t *testing.T) {
+	sink := &recordingSink{}
+	p := NewPlayer(sink)
+	p.Apply(true, allSounds(config.SoundConfig{File: writeWav(t, 8000), Volume: 0.5}))
+
+	p.Play(SoundLongBreakComplete)
+	calls := sink.calls()
+	if len(calls) != 1 || calls[0].SampleRate != 8000 {
+		t.Fatalf("played formats = %+v, want the custom 8000 Hz file", calls)
+	}
+}
+
+func TestPlayFallsBackToEmbeddedSound(t *testing.T) {
+	unsupported := filepath.Join(t.TempDir(), "sound.flac")
+	if err := os.WriteFile(unsupported, []byte("not audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	want := embeddedFormat(t)
+
+	for name, file := range map[string]string{
+		"missing":     filepath.Join(t.TempDir(), "missing.wav"),
+		"unsupported": unsupported,
+	} {
+		t.Run(name, func(t *testing.T) {
+			sink := &recordingSink{}
+			p := NewPlayer(sink)
+			p.Apply(true, allSounds(config.SoundConfig{File: file, Volume: 1}))
+
+			p.Play(SoundWorkComplete)
+			calls := sink.calls()
+			if len(calls) != 1 || calls[0] != want {
+				t.Fatalf("played formats = %+v, want the embedded sound %+v", calls, want)
+			}
+		})
+	}
+}
+
+func TestPlayStopsAfterSinkError(t *testing.T) {
+	sink := &recordingSink{err: errors.New("no audio device")}
+	p := NewPlayer(sink)
+	p.Apply(true, allSounds(config.SoundConfig{Volume: 1}))
+
+	p.Play(SoundWorkComplete)
+	p.Play(SoundBreakComplete)
+	p.Play(SoundWorkComplete)
+	if got := len(sink.calls()); got != 1 {
+		t.Fatalf("sink calls = %d, want 1 after the first device error", got)
+	}
+}
@@ -0,0 +1,35 @@
+// Package device 通过系统音频设备播放提示音，依赖 cgo 和系统的音频库，
+// 只由程序入口引用，界面和播放逻辑不依赖具体的设备
+package device
+
+import (
+	"sync"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/speaker"
+)
+
+// 扬声器使用的采样率，其他采样率的音频播放前会重新采样
+const speakerSampleRate beep.SampleRate = 44100
+
+// Speaker 通过系统音频设备播放提示音，第一次播放时才初始化设备
+type Speaker struct {
+	once sync.Once
+	err  error
+}
+
+func (s *Speaker) Play(streamer beep.Streamer, format beep.Format) error {
+	s.once.Do(func() {
+		s.err = speaker.Init(speakerSampleRate, speakerSampleRate.N(time.Second/10))
+	})
+	if s.err != nil {
+		return s.err
+	}
+
+	if format.SampleRate != speakerSampleRate {
+		streamer = beep.Resample(4, format.SampleRate, speakerSampleRate, streamer)
+	}
+	speaker.Play(streamer)
+	return nil
+}
@@ -0,0 +1,150 @@
+// Package cli 实现不依赖图形界面的 todolist 命令行工具
+package cli
+
+import (
+	"TodoList/internal/config"
+	"TodoList/internal/storage"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+const usage = `用法: todolist [--profile 名称] <命令> [参数]
+
+命令:
+  task add <标题> [--date 日期] [--priority 优先级] [--parent ID] [--repeat 规则]
+                                                      添加任务或子任务，标题中的 #标签 会被识别
+  task list [--date 日期] [--status 状态]             列出任务
+  task move <ID> <状态>                               修改任务状态
+  task rm <ID>                                        删除任务
+  task rollover [--date 日期] [--copy]                将以前未完成的任务转到指定日期
+  timer list [--date 日期]                            列出番茄钟配置
+  timer start <名称> [--date 日期] [--task ID] [--count N]
+                                                      在终端中运行番茄钟
+  stats [--range today|week|month|all]                查看统计
+  serve [--addr 地址]                                 只运行本地 HTTP 接口
+
+日期格式为 2006-01-02，默认为今天。
+重复规则: FREQ=DAILY 每天，FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR 工作日，
+FREQ=MONTHLY;BYMONTHDAY=15 每月 15 日，FREQ=DAILY;INTERVAL=3;FROM=COMPLETION 完成 3 天后。
+`
+
+// App 命令行运行时共享的配置和数据库
+type App struct {
+	config *config.Manager
+	db     *storage.Database
+	out    io.Writer
+	errOut io.Writer
+}
+
+// Run 执行命令行并返回进程退出码
+func Run(args []string, out, errOut io.Writer) int {
+	global := flag.NewFlagSet("todolist", flag.ContinueOnError)
+	global.SetOutput(errOut)
+	global.Usage = func() { fmt.Fprint(errOut, usage) }
+	profile := global.String("profile", "", "使用独立的数据库文件")
+	if err := global.Parse(args); err != nil {
+		return 2
+	}
+
+	rest := global.Args()
+	if len(rest) == 0 {
+		fmt.Fprint(errOut, usage)
+		return 2
+	}
+
+	configManager, err := config.NewManager()
+	if err != nil {
+		fmt.Fprintln(errOut, "加载配置失败:", err)
+		return 1
+	}
+
+	dbPath, err := configManager.DatabasePath(*profile)
+	if err != nil {
+		fmt.Fprintln(errOut, err)
+		return 1
+	}
+	db, err := storage.NewDatabase(dbPath)
+	if err != nil {
+		fmt.Fprintln(errOut, "打开数据库失败:", err)
+		return 1
+	}
+	defer db.Close()
+	db.SetAutoCompleteParent(configManager.GetConfig().Tasks.AutoCompleteParent)
+
+	app := &App{config: configManager, db: db, out: out, errOut: errOut}
+
+	switch rest[0] {
+	case "task":
+		err = app.runTask(rest[1:])
+	case "timer":
+		err = app.runTimer(rest[1:])
+	case "stats":
+		err = app.runStats(rest[1:])
+	case "serve":
+		err = app.runServe(rest[1:])
+	case "help", "-h", "--help":
+		fmt.Fprint(out, usage)
+		return 0
+	default:
+		err = usageError("未知命令: %s", rest[0])
+	}
+
+	if err != nil {
+		fmt.Fprintln(errOut, "错误:", err)
+		if _, ok := err.(usageErr); ok {
+			fmt.Fprint(errOut, usage)
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+// usageErr 表示参数错误，会同时输出用法说明
+type usageErr string
+
+func (e usageErr) Error() string { return string(e) }
+
+func usageError(format string, args ...any) error {
+	return usageErr(fmt.Sprintf(format, args...))
+}
+
+// parseArgs 解析参数，允许选项出现在位置参数之后
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func newFlagSet(name string, errOut io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	return fs
+}
+
+// parseDate 解析日期参数，为空时返回今天
+func parseDate(s string) (time.Time, error) {
+	if strings.TrimSpace(s) == "" {
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()), nil
+	}
+	date, err := time.ParseInLocation(dateLayout, s, time.Local)
+	if err != nil {
+		return time.Time{}, usageError("无效的日期 %q，格式应为 2006-01-02", s)
+	}
+	return date, nil
+}
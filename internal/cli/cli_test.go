@@ -0,0 +1,257 @@
+package cli
+
+import (
+	"TodoList/internal/models"
+	"TodoList/internal/storage"
+	"bytes"
+	"database/sql"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer 可以在命令运行时从其他协程读取的输出
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// setupHome 使用临时的用户目录，返回其中默认数据库的路径
+func setupHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	return filepath.Join(home, ".pomodoro-todo", "pomodoro.db")
+}
+
+// run 执行命令行，返回退出码和输出
+func run(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	code := Run(args, &out, &errOut)
+	return code, out.String(), errOut.String()
+}
+
+// expect 执行命令行并检查退出码和输出中包含的内容
+func expect(t *testing.T, code int, want []string, args ...string) string {
+	t.Helper()
+	got, out, errOut := run(t, args...)
+	if got != code {
+		t.Fatalf("%v: exit code = %d, want %d\nstdout: %s\nstderr: %s", args, got, code, out, errOut)
+	}
+	for _, w := range want {
+		if !strings.Contains(out+errOut, w) {
+			t.Fatalf("%v: output does not contain %q\nstdout: %s\nstderr: %s", args, w, out, errOut)
+		}
+	}
+	return out
+}
+
+func TestParseArgs(t *testing.T) {
+	tests := []struct {
+		args       []string
+		positional []string
+		date       string
+	}{
+		{[]string{"写周报"}, []string{"写周报"}, ""},
+		{[]string{"--date", "2024-01-01", "写", "周报"}, []string{"写", "周报"}, "2024-01-01"},
+		// 选项可以出现在位置参数之后
+		{[]string{"写", "--date", "2024-01-01", "周报", "#工作"}, []string{"写", "周报", "#工作"}, "2024-01-01"},
+		{[]string{"写周报", "--date=2024-01-02"}, []string{"写周报"}, "2024-01-02"},
+	}
+	for _, tt := range tests {
+		fs := newFlagSet("test", io.Discard)
+		date := fs.String("date", "", "")
+		positional, err := parseArgs(fs, tt.args)
+		if err != nil || !reflect.DeepEqual(positional, tt.positional) || *date != tt.date {
+			t.Errorf("parseArgs(%q) = %q, date %q, %v, want %q, date %q", tt.args, positional, *date, err, tt.positional, tt.date)
+		}
+	}
+
+	fs := newFlagSet("test", io.Discard)
+	if _, err := parseArgs(fs, []string{"写周报", "--unknown"}); err == nil || err == flag.ErrHelp {
+		t.Errorf("parseArgs accepted an unknown flag: %v", err)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	setupHome(t)
+	tests := []struct {
+		args []string
+		code int
+		want string
+	}{
+		{nil, 2, "用法"},
+		{[]string{"help"}, 0, "用法"},
+		{[]string{"bogus"}, 2, "未知命令: bogus"},
+		{[]string{"task"}, 2, "缺少 task 子命令"},
+		{[]string{"task", "bogus"}, 2, "未知的 task 子命令"},
+		{[]string{"task", "add"}, 2, "缺少任务标题"},
+		{[]string{"task", "add", "写周报", "--date", "tomorrow"}, 2, "无效的日期"},
+		{[]string{"task", "add", "浇花", "--repeat", "FREQ=HOURLY"}, 2, "无效的重复规则"},
+		{[]string{"task", "add", "写周报", "--parent", "99"}, 1, "任务 #99 不存在"},
+		{[]string{"task", "move", "1"}, 2, "用法: task move"},
+		{[]string{"task", "move", "abc", "done"}, 2, "无效的任务 ID"},
+		{[]string{"task", "move", "99", "done"}, 1, "任务 #99 不存在"},
+		{[]string{"task", "list", "--status", "later"}, 2, "无效的任务状态"},
+		{[]string{"stats", "--range", "year"}, 2, "无效的统计范围"},
+		{[]string{"timer"}, 2, "缺少 timer 子命令"},
+		{[]string{"timer", "start"}, 2, "缺少番茄钟名称"},
+		{[]string{"timer", "start", "专注"}, 1, "没有名为"},
+		{[]string{"--profile", "../work", "task", "list"}, 1, "无效的 profile"},
+	}
+	for _, tt := range tests {
+		expect(t, tt.code, []string{tt.want}, tt.args...)
+	}
+}
+
+func TestTaskCommands(t *testing.T) {
+	setupHome(t)
+
+	expect(t, 0, []string{"已添加任务 #1: 写周报 (2024-01-01)"}, "task", "add", "写周报", "#工作", "--date", "2024-01-01", "--priority", "3")
+	expect(t, 0, []string{"已添加任务 #2: 整理数据 (2024-01-01)"}, "task", "add", "整理数据", "--parent", "1")
+	expect(t, 0, []string{"已添加重复任务 #3: 浇花 (每天，从 2024-01-01 开始)"}, "task", "add", "浇花", "--date", "2024-01-01", "--repeat", "FREQ=DAILY")
+	expect(t, 0, []string{"已添加任务 #4: 读书 (2024-01-01)"}, "task", "add", "读书", "--date", "2024-01-01")
+
+	out := expect(t, 0, []string{"ID", "写周报 #工作", "└ 整理数据", "浇花", "读书"}, "task", "list", "--date", "2024-01-01")
+	if strings.Index(out, "写周报") > strings.Index(out, "整理数据") {
+		t.Fatalf("subtask listed before its parent:\n%s", out)
+	}
+	// 重复任务在列出时生成
+	expect(t, 0, []string{"浇花"}, "task", "list", "--date", "2024-01-02")
+
+	expect(t, 0, []string{"任务 #1 已移动到 DONE"}, "task", "move", "1", "done")
+	// 兼容旧的状态名称
+	expect(t, 0, []string{"任务 #1 已移动到 DONE"}, "task", "move", "#1", "completed")
+	expect(t, 1, []string{"不允许的状态变更"}, "task", "move", "1", "cancelled")
+	out = expect(t, 0, []string{"写周报"}, "task", "list", "--date", "2024-01-01", "--status", "done")
+	if strings.Contains(out, "读书") {
+		t.Fatalf("status filter kept other tasks:\n%s", out)
+	}
+
+	expect(t, 0, []string{"已删除任务 #3: 浇花"}, "task", "rm", "3")
+	expect(t, 0, []string{"已将 1 个未完成的任务转到 2024-01-03"}, "task", "rollover", "--date", "2024-01-03")
+	expect(t, 0, []string{"读书 (推迟 1 次，最初 2024-01-01)"}, "task", "list", "--date", "2024-01-03")
+
+	expect(t, 0, []string{
+		"任务总数: ",
+		"推迟过的任务: 1 (共 1 次)",
+		"#工作  1/1",
+		"推迟最多的任务:",
+		"读书  1 次 (最初 2024-01-01)",
+	}, "stats", "--range", "all")
+}
+
+// addTimer 在数据库中保存 2024-01-01 的番茄钟配置，休息不会自动开始
+func addTimer(t *testing.T, path, name string, work time.Duration) {
+	t.Helper()
+	db, err := storage.NewDatabase(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	manual := false
+	tc := &models.TimerConfig{
+		Name:           name,
+		WorkDuration:   work,
+		BreakDuration:  time.Minute,
+		LongBreak:      time.Minute,
+		Date:           time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local),
+		AutoStartBreak: &manual,
+	}
+	if err := db.SaveTimerConfig(tc); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// countRecords 读取数据库中完成或被打断的番茄钟记录数
+func countRecords(t *testing.T, path string, interrupted bool) int {
+	t.Helper()
+	conn, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var n int
+	err = conn.QueryRow(`SELECT COUNT(*) FROM pomodoro_records WHERE interrupted = ?`, interrupted).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestTimerCommands(t *testing.T) {
+	path := setupHome(t)
+	expect(t, 0, nil, "task", "list")
+	addTimer(t, path, "快速", time.Second)
+
+	expect(t, 0, []string{"名称", "快速", "1s"}, "timer", "list", "--date", "2024-01-01")
+	// 完成指定数量后退出，不等待开始休息
+	expect(t, 0, []string{"快速  工作时间", "已完成 0"}, "timer", "start", "快速", "--date", "2024-01-01", "--count", "1")
+	if got := countRecords(t, path, false); got != 1 {
+		t.Fatalf("completed records = %d, want 1", got)
+	}
+	expect(t, 0, []string{"番茄钟: 1 个"}, "stats", "--range", "all")
+}
+
+func TestTimerInterrupt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("不能向自己发送中断信号")
+	}
+	path := setupHome(t)
+	expect(t, 0, nil, "task", "list")
+	addTimer(t, path, "专注", time.Hour)
+
+	var out, errOut syncBuffer
+	code := make(chan int, 1)
+	go func() { code <- Run([]string{"timer", "start", "专注", "--date", "2024-01-01"}, &out, &errOut) }()
+
+	// 开始计时后按下 Ctrl+C
+	deadline := time.Now().Add(3 * time.Second)
+	for !strings.Contains(out.String(), "专注  工作时间") {
+		if time.Now().After(deadline) {
+			t.Fatalf("timer did not start\nstdout: %s\nstderr: %s", out.String(), errOut.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond)
+	p, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case got := <-code:
+		if got != 0 {
+			t.Fatalf("exit code = %d, want 0\nstderr: %s", got, errOut.String())
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("timer did not stop after interrupt")
+	}
+	// 返回前已经保存了被打断的记录
+	if got := countRecords(t, path, true); got != 1 {
+		t.Fatalf("interrupted records = %d, want 1", got)
+	}
+}
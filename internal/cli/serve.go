@@ -0,0 +1,56 @@
+package cli
+
+import (
+	"TodoList/internal/api"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// runServe 不启动图形界面，只运行本地 HTTP 接口
+func (a *App) runServe(args []string) error {
+	fs := newFlagSet("serve", a.errOut)
+	addr := fs.String("addr", "", "监听地址，默认使用配置文件中的 api.address")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	cfg := a.config.GetConfig()
+	apiConfig := cfg.API
+	if *addr != "" {
+		apiConfig.Address = *addr
+	}
+	token, err := a.config.EnsureAPIToken()
+	if err != nil {
+		return err
+	}
+	apiConfig.Token = token
+
+	timers := api.NewEngineTimers(a.db, cfg.Pomodoro)
+	defer timers.Close()
+
+	server, err := api.NewServer(a.db, timers, apiConfig)
+	if err != nil {
+		return err
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	errc := make(chan error, 1)
+	go func() { errc <- server.ListenAndServe() }()
+	fmt.Fprintf(a.out, "本地接口已在 http://%s 启动，按 Ctrl+C 退出\n", server.Addr())
+
+	select {
+	case err := <-errc:
+		return err
+	case <-interrupt:
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return server.Shutdown(ctx)
+}
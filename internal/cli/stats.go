@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+)
+
+func (a *App) runStats(args []string) error {
+	fs := newFlagSet("stats", a.errOut)
+	rangeFlag := fs.String("range", "today", "统计范围: today、week、month 或 all")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	startDate, endDate, err := statsRange(*rangeFlag, time.Now())
+	if err != nil {
+		return err
+	}
+
+	taskStats, err := a.db.GetTaskStats(startDate, endDate)
+	if err != nil {
+		return err
+	}
+	pomodoroStats, err := a.db.GetPomodoroStats(startDate, endDate)
+	if err != nil {
+		return err
+	}
+	tagStats, err := a.db.GetTagStats(startDate, endDate)
+	if err != nil {
+		return err
+	}
+	rolledOver, err := a.db.GetMostRolledOver(startDate, endDate, 10)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "统计范围: %s ~ %s\n\n", startDate.Format(dateLayout), endDate.Format(dateLayout))
+	fmt.Fprintf(a.out, "任务总数: %d\n完成: %d\n完成率: %.1f%%\nTodo: %d  Doing: %d  Done: %d  Cancelled: %d\n子任务: %d/%d\n推迟过的任务: %d (共 %d 次)\n\n",
+		taskStats.TotalTasks,
+		taskStats.CompletedTasks,
+		taskStats.CompletionRate,
+		taskStats.TodoTasks,
+		taskStats.DoingTasks,
+		taskStats.DoneTasks,
+		taskStats.CancelledTasks,
+		taskStats.DoneSubtasks,
+		taskStats.TotalSubtasks,
+		taskStats.RolledOverTasks,
+		taskStats.TotalRollovers,
+	)
+	fmt.Fprintf(a.out, "番茄钟: %d 个\n专注时长: %.1f 小时\n平均时长: %.1f 分钟\n",
+		pomodoroStats.TotalSessions,
+		float64(pomodoroStats.TotalDuration)/3600,
+		pomodoroStats.AverageDuration/60,
+	)
+
+	if len(tagStats) > 0 {
+		fmt.Fprintln(a.out, "\n标签:")
+		for _, stat := range tagStats {
+			fmt.Fprintf(a.out, "  #%s  %d/%d\n", stat.Tag, stat.Done, stat.Total)
+		}
+	}
+
+	if len(rolledOver) > 0 {
+		fmt.Fprintln(a.out, "\n推迟最多的任务:")
+		for _, task := range rolledOver {
+			fmt.Fprintf(a.out, "  #%d %s  %d 次 (最初 %s)\n", task.ID, task.Title, task.RolloverCount, task.OriginalDate)
+		}
+	}
+	return nil
+}
+
+// statsRange 根据范围名称计算统计的起止时间
+func statsRange(name string, now time.Time) (time.Time, time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch name {
+	case "today", "day":
+		return today, now, nil
+	case "week":
+		return today.AddDate(0, 0, -int(today.Weekday())), now, nil
+	case "month":
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()), now, nil
+	case "all":
+		return time.Time{}, now, nil
+	}
+	return time.Time{}, time.Time{}, usageError("无效的统计范围 %q，可选值: today、week、month、all", name)
+}
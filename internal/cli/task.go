@@ -0,0 +1,241 @@
+package cli
+
+import (
+	"TodoList/internal/config"
+	"TodoList/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+func (a *App) runTask(args []string) error {
+	if len(args) == 0 {
+		return usageError("缺少 task 子命令")
+	}
+
+	switch args[0] {
+	case "add":
+		return a.taskAdd(args[1:])
+	case "list", "ls":
+		return a.taskList(args[1:])
+	case "move", "mv":
+		return a.taskMove(args[1:])
+	case "rm", "remove":
+		return a.taskRemove(args[1:])
+	case "rollover":
+		return a.taskRollover(args[1:])
+	}
+	return usageError("未知的 task 子命令: %s", args[0])
+}
+
+func (a *App) taskAdd(args []string) error {
+	fs := newFlagSet("task add", a.errOut)
+	dateFlag := fs.String("date", "", "任务日期")
+	priority := fs.Int("priority", 1, "优先级")
+	parentFlag := fs.String("parent", "", "父任务 ID，子任务与父任务在同一天")
+	repeat := fs.String("repeat", "", "重复规则，如 FREQ=DAILY 或 FREQ=WEEKLY;BYDAY=MO,FR")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	title, tags := models.ParseTags(strings.Join(positional, " "))
+	if title == "" {
+		return usageError("缺少任务标题")
+	}
+	date, err := parseDate(*dateFlag)
+	if err != nil {
+		return err
+	}
+
+	task := &models.Task{
+		Title:     title,
+		Status:    models.StatusTodo,
+		CreatedAt: time.Now(),
+		Priority:  *priority,
+		Date:      date.Format(dateLayout),
+		Tags:      tags,
+	}
+	if *parentFlag != "" {
+		parent, err := a.findTask(*parentFlag)
+		if err != nil {
+			return err
+		}
+		task.ParentID = &parent.ID
+	}
+
+	var rule *models.RecurrenceRule
+	if *repeat != "" {
+		if rule, err = models.ParseRecurrenceRule(*repeat); err != nil {
+			return usageError("%v", err)
+		}
+	}
+
+	if err := a.db.SaveRecurringTask(task, rule, false); err != nil {
+		return err
+	}
+	if rule != nil {
+		fmt.Fprintf(a.out, "已添加重复任务 #%d: %s (%s，从 %s 开始)\n", task.ID, task.Title, rule.Describe(), task.Date)
+		return nil
+	}
+
+	fmt.Fprintf(a.out, "已添加任务 #%d: %s (%s)\n", task.ID, task.Title, task.Date)
+	return nil
+}
+
+func (a *App) taskList(args []string) error {
+	fs := newFlagSet("task list", a.errOut)
+	dateFlag := fs.String("date", "", "任务日期")
+	statusFlag := fs.String("status", "", "只显示指定状态的任务")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	date, err := parseDate(*dateFlag)
+	if err != nil {
+		return err
+	}
+
+	var status models.TaskStatus
+	if *statusFlag != "" {
+		if status, err = parseStatus(*statusFlag); err != nil {
+			return err
+		}
+	}
+
+	if err := a.db.MaterializeRecurrences(date.Format(dateLayout)); err != nil {
+		return err
+	}
+	tasks, err := a.db.GetTasksByDate(date.Format(dateLayout))
+	if err != nil {
+		return err
+	}
+
+	// 子任务缩进显示在父任务下面
+	subtasks := make(map[int64][]*models.Task)
+	for _, task := range tasks {
+		if task.ParentID != nil {
+			subtasks[*task.ParentID] = append(subtasks[*task.ParentID], task)
+		}
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\t状态\t优先级\t标题")
+	for _, task := range tasks {
+		if task.ParentID != nil {
+			continue
+		}
+		if status == "" || task.Status == status {
+			title := taskTitle(task)
+			if task.RolloverCount > 0 {
+				title += fmt.Sprintf(" (推迟 %d 次，最初 %s)", task.RolloverCount, task.OriginalDate)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%d\t%s\n", task.ID, task.Status, task.Priority, title)
+		}
+		for _, sub := range subtasks[task.ID] {
+			if status == "" || sub.Status == status {
+				fmt.Fprintf(w, "%d\t%s\t%d\t  └ %s\n", sub.ID, sub.Status, sub.Priority, taskTitle(sub))
+			}
+		}
+	}
+	return w.Flush()
+}
+
+func (a *App) taskMove(args []string) error {
+	if len(args) != 2 {
+		return usageError("用法: task move <ID> <状态>")
+	}
+
+	task, err := a.findTask(args[0])
+	if err != nil {
+		return err
+	}
+	status, err := parseStatus(args[1])
+	if err != nil {
+		return err
+	}
+
+	if err := task.SetStatus(status, time.Now()); err != nil {
+		return err
+	}
+	if err := a.db.SaveTask(task); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "任务 #%d 已移动到 %s\n", task.ID, task.Status)
+	return nil
+}
+
+func (a *App) taskRemove(args []string) error {
+	if len(args) != 1 {
+		return usageError("用法: task rm <ID>")
+	}
+
+	task, err := a.findTask(args[0])
+	if err != nil {
+		return err
+	}
+	if err := a.db.DeleteTask(task.ID); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "已删除任务 #%d: %s\n", task.ID, task.Title)
+	return nil
+}
+
+// taskRollover 将以前未完成的任务转到指定日期，默认使用配置中的转移方式
+func (a *App) taskRollover(args []string) error {
+	fs := newFlagSet("task rollover", a.errOut)
+	dateFlag := fs.String("date", "", "转到的日期")
+	copyFlag := fs.Bool("copy", a.config.GetConfig().Tasks.RolloverMode == config.RolloverCopy, "创建副本，保留原来的任务")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	date, err := parseDate(*dateFlag)
+	if err != nil {
+		return err
+	}
+	count, err := a.db.RolloverTasks(date.Format(dateLayout), *copyFlag)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "已将 %d 个未完成的任务转到 %s\n", count, date.Format(dateLayout))
+	return nil
+}
+
+// taskTitle 返回带 #标签 的任务标题
+func taskTitle(task *models.Task) string {
+	title := task.Title
+	for _, tag := range task.Tags {
+		title += " #" + tag
+	}
+	return title
+}
+
+// findTask 根据命令行中的 ID 查找任务
+func (a *App) findTask(arg string) (*models.Task, error) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(arg, "#"), 10, 64)
+	if err != nil {
+		return nil, usageError("无效的任务 ID: %s", arg)
+	}
+
+	task, err := a.db.GetTaskByID(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("任务 #%d 不存在", id)
+	}
+	return task, err
+}
+
+func parseStatus(s string) (models.TaskStatus, error) {
+	status, err := models.ParseTaskStatus(s)
+	if err != nil {
+		return "", usageError("%v", err)
+	}
+	return status, nil
+}
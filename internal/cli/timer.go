@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"TodoList/internal/models"
+	"TodoList/internal/timer"
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// 终端中显示的阶段名称
+var phaseNames = map[timer.Phase]string{
+	timer.PhaseWork:       "工作时间",
+	timer.PhaseShortBreak: "休息时间",
+	timer.PhaseLongBreak:  "长休息时间",
+}
+
+func (a *App) runTimer(args []string) error {
+	if len(args) == 0 {
+		return usageError("缺少 timer 子命令")
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		return a.timerList(args[1:])
+	case "start":
+		return a.timerStart(args[1:])
+	}
+	return usageError("未知的 timer 子命令: %s", args[0])
+}
+
+func (a *App) timerList(args []string) error {
+	fs := newFlagSet("timer list", a.errOut)
+	dateFlag := fs.String("date", "", "配置日期")
+	if _, err := parseArgs(fs, args); err != nil {
+		return err
+	}
+
+	date, err := parseDate(*dateFlag)
+	if err != nil {
+		return err
+	}
+
+	configs, err := a.db.GetTimerConfigsByDate(date)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "名称\t工作\t休息\t长休息")
+	for _, c := range configs {
+		fmt.Fprintf(w, "%s\t%v\t%v\t%v\n", c.Name, c.WorkDuration, c.BreakDuration, c.LongBreak)
+	}
+	return w.Flush()
+}
+
+func (a *App) timerStart(args []string) error {
+	fs := newFlagSet("timer start", a.errOut)
+	dateFlag := fs.String("date", "", "配置日期")
+	taskFlag := fs.String("task", "", "关联的任务 ID")
+	count := fs.Int("count", 0, "完成指定数量的番茄钟后退出，0 表示一直运行")
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+
+	name := strings.TrimSpace(strings.Join(positional, " "))
+	if name == "" {
+		return usageError("缺少番茄钟名称")
+	}
+	date, err := parseDate(*dateFlag)
+	if err != nil {
+		return err
+	}
+
+	tc, err := a.db.GetTimerConfigByName(name, date)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%s 没有名为 %q 的番茄钟", date.Format(dateLayout), name)
+	}
+	if err != nil {
+		return err
+	}
+
+	var task *models.Task
+	if *taskFlag != "" {
+		if task, err = a.findTask(*taskFlag); err != nil {
+			return err
+		}
+	}
+
+	engine := timer.NewEngine(timer.NewConfig(tc, a.config.GetConfig().Pomodoro), nil)
+
+	done := make(chan struct{})
+	engine.Subscribe(a.countdownListener(engine, task, *count, done, bufio.NewReader(os.Stdin)))
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	fmt.Fprintf(a.out, "%s  %s\n", tc.Name, phaseNames[timer.PhaseWork])
+	if task != nil {
+		fmt.Fprintf(a.out, "专注: %s\n", task.Title)
+	}
+	engine.Start()
+
+	select {
+	case <-done:
+	case <-interrupt:
+		// 中途退出时记录被打断的工作时段
+		engine.Reset()
+	}
+	// 事件可能还在计时协程中发送，等记录保存完再返回，避免程序退出时丢失
+	engine.Drain()
+	fmt.Fprintln(a.out)
+	return nil
+}
+
+// countdownListener 在终端中刷新倒计时并记录完成的工作时段
+// 没有开启自动开始时，从 input 读到回车后再开始下一阶段
+func (a *App) countdownListener(engine *timer.Engine, task *models.Task, count int, done chan struct{}, input *bufio.Reader) timer.Listener {
+	var taskID int64
+	if task != nil {
+		taskID = task.ID
+	}
+
+	var finish sync.Once
+	return func(ev timer.Event) {
+		switch ev.Type {
+		case timer.EventTick:
+			fmt.Fprintf(a.out, "\r%s  %s  已完成 %d", phaseNames[ev.Phase], formatRemaining(ev.Remaining), ev.Completed)
+
+		case timer.EventPhaseCompleted, timer.EventPhaseInterrupted:
+			if record := ev.Record(taskID); record != nil {
+				if err := a.db.SavePomodoroRecord(record); err != nil {
+					fmt.Fprintln(a.errOut, "\n保存番茄钟记录失败:", err)
+				}
+			}
+			if ev.Type == timer.EventPhaseCompleted {
+				// 终端响铃提示
+				fmt.Fprint(a.out, "\a")
+			}
+
+		case timer.EventPhaseStarted:
+			fmt.Fprintf(a.out, "\n%s  %s", phaseNames[ev.Phase], formatRemaining(ev.Remaining))
+			if count > 0 && ev.Completed >= count {
+				engine.Pause()
+				finish.Do(func() { close(done) })
+			}
+
+		case timer.EventPhaseWaiting:
+			if count > 0 && ev.Completed >= count {
+				finish.Do(func() { close(done) })
+				return
+			}
+			fmt.Fprintf(a.out, "  按回车开始%s", phaseNames[ev.Phase])
+			go func() {
+				// 输入已关闭时直接开始，避免非交互环境中一直等待
+				input.ReadString('\n')
+				engine.Start()
+			}()
+		}
+	}
+}
+
+func formatRemaining(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%02d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
@@ -1,9 +1,17 @@
 package config
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/fsnotify/fsnotify"
 	"gopkg.in/yaml.v3"
+	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -12,13 +20,16 @@ type Config struct {
 	Pomodoro PomodoroConfig `yaml:"pomodoro"`
 	Database DatabaseConfig `yaml:"database"`
 	Theme    ThemeConfig    `yaml:"theme"`
+	API      APIConfig      `yaml:"api"`
+	Tasks    TasksConfig    `yaml:"tasks"`
 }
 
 type AppConfig struct {
-	Name         string `yaml:"name"`
-	Version      string `yaml:"version"`
-	WindowWidth  int    `yaml:"window_width"`
-	WindowHeight int    `yaml:"window_height"`
+	Name           string `yaml:"name"`
+	Version        string `yaml:"version"`
+	WindowWidth    int    `yaml:"window_width"`
+	WindowHeight   int    `yaml:"window_height"`
+	MinimizeToTray bool   `yaml:"minimize_to_tray"` // 关闭窗口时隐藏到系统托盘，计时器继续运行
 }
 
 type PomodoroConfig struct {
@@ -29,16 +40,89 @@ type PomodoroConfig struct {
 	AutoStartBreak    bool          `yaml:"auto_start_break"`
 	AutoStartPomodoro bool          `yaml:"auto_start_pomodoro"`
 	NotificationSound bool          `yaml:"notification_sound"`
+	SuspendPolicy     string        `yaml:"suspend_policy"` // 系统休眠时的处理方式: count、pause 或 end
+	Sounds            SoundsConfig  `yaml:"sounds"`
+	Notifications     NotifyConfig  `yaml:"notifications"`
+}
+
+// NotifyConfig 各阶段结束时是否发送桌面通知
+type NotifyConfig struct {
+	WorkComplete      bool `yaml:"work_complete"`
+	BreakComplete     bool `yaml:"break_complete"`
+	LongBreakComplete bool `yaml:"long_break_complete"`
+}
+
+// SoundsConfig 各阶段结束时播放的提示音
+type SoundsConfig struct {
+	WorkComplete      SoundConfig `yaml:"work_complete"`
+	BreakComplete     SoundConfig `yaml:"break_complete"`
+	LongBreakComplete SoundConfig `yaml:"long_break_complete"`
+}
+
+// SoundConfig 一种提示音的文件和音量
+type SoundConfig struct {
+	File   string  `yaml:"file"`   // wav、mp3 或 ogg 文件，为空时使用各事件共用的内置提示音
+	Volume float64 `yaml:"volume"` // 0 到 1，0 表示静音
+}
+
+// TasksConfig 待办事项相关设置
+type TasksConfig struct {
+	AutoCompleteParent bool   `yaml:"auto_complete_parent"` // 子任务全部完成后自动完成父任务
+	AutoRollover       bool   `yaml:"auto_rollover"`        // 启动时将以前未完成的任务转到今天
+	RolloverMode       string `yaml:"rollover_mode"`        // 转移方式: move 或 copy
 }
 
 type DatabaseConfig struct {
+	// 数据库文件路径，支持 ~ 和环境变量，相对路径相对于配置目录；
+	// 配置目录中没有而工作目录中有同名文件时使用工作目录中的旧数据库
 	Path string `yaml:"path"`
 }
 
 type ThemeConfig struct {
-	DarkMode bool   `yaml:"dark_mode"`
-	FontSize int    `yaml:"font_size"`
-	Language string `yaml:"language"`
+	Mode        string `yaml:"mode"` // light、dark 或 system，为空时沿用 dark_mode
+	DarkMode    bool   `yaml:"dark_mode"`
+	FontSize    int    `yaml:"font_size"`
+	AccentColor string `yaml:"accent_color"` // 强调色，如 #ff4081，为空时使用默认主题色
+	Language    string `yaml:"language"`
+}
+
+// APIConfig 本地 HTTP 接口配置
+type APIConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	Address     string `yaml:"address"`      // 监听地址，默认只监听本机
+	Token       string `yaml:"token"`        // 访问令牌，为空时启动时自动生成
+	AllowRemote bool   `yaml:"allow_remote"` // 是否允许监听非本机地址
+}
+
+// 主题模式
+const (
+	ThemeModeLight  = "light"
+	ThemeModeDark   = "dark"
+	ThemeModeSystem = "system"
+)
+
+// 系统休眠时正在计时的阶段的处理方式
+const (
+	SuspendCount = "count" // 休眠时间照常计入
+	SuspendPause = "pause" // 暂停计时
+	SuspendEnd   = "end"   // 结束当前阶段
+)
+
+// 未完成的任务转到今天的方式
+const (
+	RolloverMove = "move" // 修改任务的日期
+	RolloverCopy = "copy" // 在今天创建副本，原来的任务保留在原来的日期
+)
+
+// EffectiveMode 返回实际使用的主题模式，未设置 mode 时沿用 dark_mode
+func (t ThemeConfig) EffectiveMode() string {
+	if t.Mode != "" {
+		return t.Mode
+	}
+	if t.DarkMode {
+		return ThemeModeDark
+	}
+	return ThemeModeLight
 }
 
 // 默认配置
@@ -58,21 +142,47 @@ func DefaultConfig() *Config {
 			AutoStartBreak:    false,
 			AutoStartPomodoro: false,
 			NotificationSound: true,
+			SuspendPolicy:     SuspendCount,
+			Sounds: SoundsConfig{
+				WorkComplete:      SoundConfig{Volume: 1},
+				BreakComplete:     SoundConfig{Volume: 1},
+				LongBreakComplete: SoundConfig{Volume: 1},
+			},
+			Notifications: NotifyConfig{
+				WorkComplete:      true,
+				BreakComplete:     true,
+				LongBreakComplete: true,
+			},
 		},
 		Database: DatabaseConfig{
-			Path: "pomodoro.db",
+			Path: "~/.pomodoro-todo/pomodoro.db",
 		},
 		Theme: ThemeConfig{
 			DarkMode: false,
 			FontSize: 14,
 			Language: "zh-CN",
 		},
+		API: APIConfig{
+			Enabled: false,
+			Address: "127.0.0.1:7788",
+		},
+		Tasks: TasksConfig{
+			AutoCompleteParent: true,
+			RolloverMode:       RolloverMove,
+		},
 	}
 }
 
 type Manager struct {
+	mu         sync.RWMutex
 	config     *Config
 	configPath string
+	lastData   []byte // 最近一次读取或写入的文件内容，用于忽略未变化的文件事件
+
+	callbacks []ConfigChangeCallback
+	onError   func(error)
+	watchOnce sync.Once
+	watcher   *fsnotify.Watcher
 }
 
 func NewManager() (*Manager, error) {
@@ -89,6 +199,11 @@ func NewManager() (*Manager, error) {
 	// 加载或创建配置
 	if err := manager.loadConfig(); err != nil {
 		manager.config = DefaultConfig()
+		if !os.IsNotExist(err) {
+			// 配置文件无效时使用默认配置，但不覆盖用户的文件
+			log.Printf("配置文件 %s 无效，使用默认配置: %v", configPath, err)
+			return manager, nil
+		}
 		if err := manager.SaveConfig(); err != nil {
 			return nil, err
 		}
@@ -103,16 +218,97 @@ func (m *Manager) loadConfig() error {
 		return err
 	}
 
-	config := &Config{}
-	if err := yaml.Unmarshal(data, config); err != nil {
+	config, err := parseConfig(data)
+	if err != nil {
 		return err
 	}
 
+	m.mu.Lock()
 	m.config = config
+	m.lastData = data
+	m.mu.Unlock()
+	return nil
+}
+
+// parseConfig 解析并校验配置，文件中缺失的字段使用默认值
+func parseConfig(data []byte) (*Config, error) {
+	config := DefaultConfig()
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate 校验配置是否合法
+func (c *Config) Validate() error {
+	p := c.Pomodoro
+	if p.WorkDuration <= 0 {
+		return fmt.Errorf("pomodoro.work_duration 必须大于 0，当前为 %v", p.WorkDuration)
+	}
+	if p.ShortBreak <= 0 {
+		return fmt.Errorf("pomodoro.short_break 必须大于 0，当前为 %v", p.ShortBreak)
+	}
+	if p.LongBreak <= 0 {
+		return fmt.Errorf("pomodoro.long_break 必须大于 0，当前为 %v", p.LongBreak)
+	}
+	if p.LongBreakAfter < 1 {
+		return fmt.Errorf("pomodoro.long_break_after 必须至少为 1，当前为 %d", p.LongBreakAfter)
+	}
+	switch p.SuspendPolicy {
+	case SuspendCount, SuspendPause, SuspendEnd:
+	default:
+		return fmt.Errorf("pomodoro.suspend_policy 只能是 count、pause 或 end，当前为 %q", p.SuspendPolicy)
+	}
+	for key, sound := range map[string]SoundConfig{
+		"work_complete":       p.Sounds.WorkComplete,
+		"break_complete":      p.Sounds.BreakComplete,
+		"long_break_complete": p.Sounds.LongBreakComplete,
+	} {
+		if sound.Volume < 0 || sound.Volume > 1 {
+			return fmt.Errorf("pomodoro.sounds.%s.volume 必须在 0 到 1 之间，当前为 %v", key, sound.Volume)
+		}
+		switch strings.ToLower(filepath.Ext(sound.File)) {
+		case "", ".wav", ".mp3", ".ogg":
+		default:
+			return fmt.Errorf("pomodoro.sounds.%s.file 只支持 wav、mp3 和 ogg 文件，当前为 %q", key, sound.File)
+		}
+	}
+	switch c.Theme.Mode {
+	case "", ThemeModeLight, ThemeModeDark, ThemeModeSystem:
+	default:
+		return fmt.Errorf("theme.mode 只能是 light、dark 或 system，当前为 %q", c.Theme.Mode)
+	}
+	if c.Theme.AccentColor != "" && !hexColor.MatchString(c.Theme.AccentColor) {
+		return fmt.Errorf("theme.accent_color 必须是 #RRGGBB 格式，当前为 %q", c.Theme.AccentColor)
+	}
+	if c.Theme.FontSize < 8 || c.Theme.FontSize > 48 {
+		return fmt.Errorf("theme.font_size 必须在 8 到 48 之间，当前为 %d", c.Theme.FontSize)
+	}
+	if c.API.Enabled && c.API.Address == "" {
+		return fmt.Errorf("api.address 不能为空")
+	}
+	switch c.Tasks.RolloverMode {
+	case RolloverMove, RolloverCopy:
+	default:
+		return fmt.Errorf("tasks.rollover_mode 只能是 move 或 copy，当前为 %q", c.Tasks.RolloverMode)
+	}
+	if c.App.WindowWidth <= 0 || c.App.WindowHeight <= 0 {
+		return fmt.Errorf("app.window_width 和 app.window_height 必须大于 0")
+	}
 	return nil
 }
 
 func (m *Manager) SaveConfig() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.saveLocked()
+}
+
+// saveLocked 写入配置文件，调用时必须持有锁
+func (m *Manager) saveLocked() error {
 	data, err := yaml.Marshal(m.config)
 	if err != nil {
 		return err
@@ -124,10 +320,16 @@ func (m *Manager) SaveConfig() error {
 		return err
 	}
 
-	return os.WriteFile(m.configPath, data, 0644)
+	if err := os.WriteFile(m.configPath, data, 0644); err != nil {
+		return err
+	}
+	m.lastData = data
+	return nil
 }
 
 func (m *Manager) GetConfig() *Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
 	return m.config
 }
 
@@ -143,21 +345,121 @@ func getConfigDir() (string, error) {
 	return configDir, nil
 }
 
+var hexColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}([0-9a-fA-F]{2})?$`)
+
+// profile 名称只允许字母、数字、下划线和短横线
+var profileName = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// DatabasePath 返回数据库文件的绝对路径，profile 不为空时使用独立的数据库文件
+func (m *Manager) DatabasePath(profile string) (string, error) {
+	path := m.GetConfig().Database.Path
+	if path == "" {
+		path = DefaultConfig().Database.Path
+	}
+
+	path, err := ExpandPath(path)
+	if err != nil {
+		return "", err
+	}
+	if profile != "" {
+		if !profileName.MatchString(profile) {
+			return "", fmt.Errorf("无效的 profile 名称: %q", profile)
+		}
+		// pomodoro.db -> pomodoro-work.db
+		ext := filepath.Ext(path)
+		path = strings.TrimSuffix(path, ext) + "-" + profile + ext
+	}
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+
+	// 以前的版本中相对路径相对于工作目录，配置目录中还没有数据库而工作目录中有时继续使用原来的数据库
+	resolved := filepath.Join(filepath.Dir(m.configPath), path)
+	legacy, err := filepath.Abs(path)
+	if err == nil && legacy != resolved && !fileExists(resolved) && fileExists(legacy) {
+		log.Printf("数据库 %s 不存在，继续使用工作目录中的 %s；如需改用配置目录，请将它移动到 %s", resolved, legacy, resolved)
+		return legacy, nil
+	}
+	return resolved, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// ResolvePath 展开路径，相对路径相对于配置文件所在的目录
+func (m *Manager) ResolvePath(path string) (string, error) {
+	path, err := ExpandPath(path)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(m.configPath), path)
+	}
+	return path, nil
+}
+
+// ExpandPath 展开路径中的环境变量和开头的 ~
+func ExpandPath(path string) (string, error) {
+	path = os.ExpandEnv(path)
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path, nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, path[1:]), nil
+}
+
 // 更新配置的便捷方法
 func (m *Manager) UpdatePomodoroConfig(config PomodoroConfig) error {
-	m.config.Pomodoro = config
-	return m.SaveConfig()
+	return m.update(func(c *Config) { c.Pomodoro = config })
 }
 
 func (m *Manager) UpdateThemeConfig(config ThemeConfig) error {
-	m.config.Theme = config
-	return m.SaveConfig()
+	return m.update(func(c *Config) { c.Theme = config })
 }
 
-// 监听配置变化
-type ConfigChangeCallback func(*Config)
+// EnsureAPIToken 返回接口访问令牌，未设置时生成一个随机令牌并保存
+func (m *Manager) EnsureAPIToken() (string, error) {
+	if token := m.GetConfig().API.Token; token != "" {
+		return token, nil
+	}
 
-func (m *Manager) WatchConfig(callback ConfigChangeCallback) {
-	// TODO: 实现配置文件监听
-	// 可以使用 fsnotify 包来监听配置文件变化
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	if err := m.update(func(c *Config) { c.API.Token = token }); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// update 在配置副本上修改、校验并保存，成功后通知监听者
+func (m *Manager) update(modify func(*Config)) error {
+	m.mu.Lock()
+	config := *m.config
+	modify(&config)
+	if err := config.Validate(); err != nil {
+		m.mu.Unlock()
+		return err
+	}
+
+	previous := m.config
+	m.config = &config
+	if err := m.saveLocked(); err != nil {
+		m.config = previous
+		m.mu.Unlock()
+		return err
+	}
+	m.mu.Unlock()
+
+	m.notify(&config)
+	return nil
 }
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestManager 在临时的用户目录中创建配置，数据库路径为 dbPath
+func newTestManager(t *testing.T, dbPath string) (*Manager, string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	m, err := NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.update(func(c *Config) { c.Database.Path = dbPath }); err != nil {
+		t.Fatal(err)
+	}
+	return m, home
+}
+
+// chdir 切换工作目录，测试结束后恢复
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestDatabasePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		profile string
+		want    string // 相对于用户目录
+	}{
+		{"", "", ".pomodoro-todo/pomodoro.db"},
+		{"pomodoro.db", "", ".pomodoro-todo/pomodoro.db"},
+		{"data/tasks.db", "work", ".pomodoro-todo/data/tasks-work.db"},
+		{"~/todo.db", "", "todo.db"},
+		{"$HOME/todo.db", "personal", "todo-personal.db"},
+	}
+	for _, tt := range tests {
+		m, home := newTestManager(t, tt.path)
+		chdir(t, t.TempDir())
+
+		got, err := m.DatabasePath(tt.profile)
+		if want := filepath.Join(home, tt.want); err != nil || got != want {
+			t.Errorf("DatabasePath(%q, %q) = %q, %v, want %q", tt.path, tt.profile, got, err, want)
+		}
+	}
+
+	m, _ := newTestManager(t, "pomodoro.db")
+	if _, err := m.DatabasePath("../work"); err == nil {
+		t.Error("DatabasePath accepted an invalid profile")
+	}
+}
+
+func TestDatabasePathKeepsLegacyWorkingDirDatabase(t *testing.T) {
+	m, home := newTestManager(t, "pomodoro.db")
+	wd := t.TempDir()
+	chdir(t, wd)
+
+	// 旧版本在工作目录中创建的数据库继续使用
+	legacy := filepath.Join(wd, "pomodoro.db")
+	if err := os.WriteFile(legacy, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := m.DatabasePath(""); err != nil || got != legacy {
+		t.Fatalf("DatabasePath = %q, %v, want legacy %q", got, err, legacy)
+	}
+
+	// 配置目录中已有数据库时使用配置目录中的
+	resolved := filepath.Join(home, ".pomodoro-todo", "pomodoro.db")
+	if err := os.WriteFile(resolved, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := m.DatabasePath(""); err != nil || got != resolved {
+		t.Fatalf("DatabasePath = %q, %v, want %q", got, err, resolved)
+	}
+}
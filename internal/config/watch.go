@@ -0,0 +1,159 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// 编辑器保存文件时往往会连续触发多个事件，合并后再重新加载
+const reloadDelay = 200 * time.Millisecond
+
+// 监听配置变化
+type ConfigChangeCallback func(*Config)
+
+// WatchConfig 注册配置变化回调，并在第一次调用时开始监听配置文件
+func (m *Manager) WatchConfig(callback ConfigChangeCallback) {
+	m.mu.Lock()
+	m.callbacks = append(m.callbacks, callback)
+	m.mu.Unlock()
+
+	m.watchOnce.Do(func() {
+		if err := m.startWatcher(); err != nil {
+			log.Printf("无法监听配置文件 %s: %v", m.configPath, err)
+		}
+	})
+}
+
+// SetOnError 设置配置文件无效时的回调，此时仍保留上一次有效的配置
+func (m *Manager) SetOnError(callback func(error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onError = callback
+}
+
+// Close 停止监听配置文件
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	watcher := m.watcher
+	m.watcher = nil
+	m.mu.Unlock()
+
+	if watcher == nil {
+		return nil
+	}
+	return watcher.Close()
+}
+
+func (m *Manager) startWatcher() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	// 监听所在目录而不是文件本身，这样编辑器以重命名方式保存时也能收到事件
+	if err := watcher.Add(filepath.Dir(m.configPath)); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	m.mu.Lock()
+	m.watcher = watcher
+	m.mu.Unlock()
+
+	go m.watchLoop(watcher)
+	return nil
+}
+
+func (m *Manager) watchLoop(watcher *fsnotify.Watcher) {
+	target := filepath.Clean(m.configPath)
+	var pending *time.Timer
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != target {
+				continue
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+
+			if pending != nil {
+				pending.Stop()
+			}
+			pending = time.AfterFunc(reloadDelay, m.reload)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("监听配置文件出错: %v", err)
+		}
+	}
+}
+
+// reload 重新读取配置文件，校验通过后通知所有回调
+func (m *Manager) reload() {
+	data, err := os.ReadFile(m.configPath)
+	if err != nil {
+		// 文件被删除或正在替换时忽略，等待下一次事件
+		if !os.IsNotExist(err) {
+			m.reportError(err)
+		}
+		return
+	}
+
+	m.mu.RLock()
+	unchanged := bytes.Equal(data, m.lastData)
+	m.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	config, err := parseConfig(data)
+	if err != nil {
+		m.mu.Lock()
+		m.lastData = data
+		m.mu.Unlock()
+		m.reportError(err)
+		return
+	}
+
+	m.mu.Lock()
+	m.config = config
+	m.lastData = data
+	m.mu.Unlock()
+
+	m.notify(config)
+}
+
+func (m *Manager) reportError(err error) {
+	err = fmt.Errorf("配置文件 %s 无效，已保留上一次的配置: %w", m.configPath, err)
+	log.Println(err)
+
+	m.mu.RLock()
+	onError := m.onError
+	m.mu.RUnlock()
+	if onError != nil {
+		onError(err)
+	}
+}
+
+func (m *Manager) notify(config *Config) {
+	m.mu.RLock()
+	callbacks := append([]ConfigChangeCallback(nil), m.callbacks...)
+	m.mu.RUnlock()
+
+	for _, callback := range callbacks {
+		callback(config)
+	}
+}
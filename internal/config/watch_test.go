@@ -0,0 +1,138 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 等待重新加载的最长时间，远大于 reloadDelay
+const watchTimeout = 3 * time.Second
+
+// watchedManager 在临时的用户目录中创建配置并开始监听，返回变化和错误的通知
+func watchedManager(t *testing.T) (*Manager, <-chan *Config, <-chan error) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	m, err := NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Close() })
+
+	changes := make(chan *Config, 10)
+	errs := make(chan error, 10)
+	m.SetOnError(func(err error) { errs <- err })
+	m.WatchConfig(func(c *Config) { changes <- c })
+	return m, changes, errs
+}
+
+// configData 返回工作时长为 work 的有效配置内容
+func configData(t *testing.T, work time.Duration) []byte {
+	t.Helper()
+	c := DefaultConfig()
+	c.Pomodoro.WorkDuration = work
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func receive[T any](t *testing.T, ch <-chan T, what string) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(watchTimeout):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+	panic("unreachable")
+}
+
+// expectQuiet 检查一段时间内没有收到通知
+func expectQuiet[T any](t *testing.T, ch <-chan T, what string) {
+	t.Helper()
+	select {
+	case v := <-ch:
+		t.Fatalf("unexpected %s: %v", what, v)
+	case <-time.After(2 * reloadDelay):
+	}
+}
+
+func TestWatchConfigKeepsLastValidConfig(t *testing.T) {
+	m, changes, errs := watchedManager(t)
+
+	writeFile(t, m.configPath, configData(t, 30*time.Minute))
+	if c := receive(t, changes, "first change"); c.Pomodoro.WorkDuration != 30*time.Minute {
+		t.Fatalf("work = %v, want 30m", c.Pomodoro.WorkDuration)
+	}
+
+	// 无效的配置报告错误，继续使用上一次有效的配置
+	writeFile(t, m.configPath, []byte("theme:\n  font_size: 99\n"))
+	receive(t, errs, "invalid config error")
+	expectQuiet(t, changes, "change for invalid config")
+	if work := m.GetConfig().Pomodoro.WorkDuration; work != 30*time.Minute {
+		t.Fatalf("work after invalid config = %v, want 30m", work)
+	}
+
+	writeFile(t, m.configPath, configData(t, 40*time.Minute))
+	if c := receive(t, changes, "second change"); c.Pomodoro.WorkDuration != 40*time.Minute {
+		t.Fatalf("work = %v, want 40m", c.Pomodoro.WorkDuration)
+	}
+	if work := m.GetConfig().Pomodoro.WorkDuration; work != 40*time.Minute {
+		t.Fatalf("effective work = %v, want 40m", work)
+	}
+	expectQuiet(t, errs, "error")
+}
+
+func TestWatchConfigDebouncesWrites(t *testing.T) {
+	m, changes, _ := watchedManager(t)
+
+	// 连续的多次写入合并为一次重新加载，使用最后的内容
+	for _, minutes := range []time.Duration{31, 32, 33, 34} {
+		writeFile(t, m.configPath, configData(t, minutes*time.Minute))
+		time.Sleep(reloadDelay / 10)
+	}
+	if c := receive(t, changes, "change"); c.Pomodoro.WorkDuration != 34*time.Minute {
+		t.Fatalf("work = %v, want 34m", c.Pomodoro.WorkDuration)
+	}
+	expectQuiet(t, changes, "second change")
+}
+
+func TestWatchConfigRenameReplace(t *testing.T) {
+	m, changes, _ := watchedManager(t)
+
+	// 编辑器先写入临时文件，再重命名覆盖原文件
+	tmp := filepath.Join(filepath.Dir(m.configPath), ".config.yaml.swp")
+	writeFile(t, tmp, configData(t, 45*time.Minute))
+	if err := os.Rename(tmp, m.configPath); err != nil {
+		t.Fatal(err)
+	}
+	if c := receive(t, changes, "change"); c.Pomodoro.WorkDuration != 45*time.Minute {
+		t.Fatalf("work = %v, want 45m", c.Pomodoro.WorkDuration)
+	}
+
+	// 替换之后继续监听新的文件
+	writeFile(t, m.configPath, configData(t, 50*time.Minute))
+	if c := receive(t, changes, "change after replace"); c.Pomodoro.WorkDuration != 50*time.Minute {
+		t.Fatalf("work = %v, want 50m", c.Pomodoro.WorkDuration)
+	}
+}
+
+func TestWatchConfigIgnoresOtherFiles(t *testing.T) {
+	m, changes, errs := watchedManager(t)
+
+	writeFile(t, filepath.Join(filepath.Dir(m.configPath), "pomodoro.db"), []byte("not yaml"))
+	expectQuiet(t, changes, "change")
+	expectQuiet(t, errs, "error")
+}
@@ -0,0 +1,284 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRecurrence = errors.New("无效的重复规则")
+
+// 重复的频率
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+)
+
+// RRULE 中的星期缩写，下标与 time.Weekday 一致
+var weekdayCodes = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// 界面中显示的星期名称
+var weekdayNames = []string{"日", "一", "二", "三", "四", "五", "六"}
+
+// WeekdayName 返回星期的中文名称，如 一、日
+func WeekdayName(day time.Weekday) string {
+	return weekdayNames[day]
+}
+
+// Weekdays 周一到周五
+var Weekdays = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+// RecurrenceRule 类似 RRULE 的重复规则，例如:
+//
+//	FREQ=DAILY                             每天
+//	FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR       工作日
+//	FREQ=MONTHLY;BYMONTHDAY=15             每月 15 日
+//	FREQ=DAILY;INTERVAL=3;FROM=COMPLETION  上一次完成 3 天后
+type RecurrenceRule struct {
+	Freq           string
+	Interval       int            // 每隔几个周期，至少为 1
+	ByDay          []time.Weekday // FREQ=WEEKLY 时重复的星期
+	MonthDay       int            // FREQ=MONTHLY 时的日期，超过当月天数时使用最后一天
+	FromCompletion bool           // 从上一次完成的日期开始计算，只支持 FREQ=DAILY
+}
+
+// ParseRecurrenceRule 解析重复规则，不区分大小写
+func ParseRecurrenceRule(s string) (*RecurrenceRule, error) {
+	rule := &RecurrenceRule{Interval: 1}
+	for _, part := range strings.Split(strings.ToUpper(strings.TrimSpace(s)), ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w %q", ErrInvalidRecurrence, s)
+		}
+
+		switch key {
+		case "FREQ":
+			rule.Freq = value
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%w: INTERVAL 必须是正整数", ErrInvalidRecurrence)
+			}
+			rule.Interval = n
+		case "BYDAY":
+			for _, code := range strings.Split(value, ",") {
+				day := indexOf(weekdayCodes, code)
+				if day < 0 {
+					return nil, fmt.Errorf("%w: 未知的星期 %q", ErrInvalidRecurrence, code)
+				}
+				rule.ByDay = append(rule.ByDay, time.Weekday(day))
+			}
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 || n > 31 {
+				return nil, fmt.Errorf("%w: BYMONTHDAY 必须在 1 到 31 之间", ErrInvalidRecurrence)
+			}
+			rule.MonthDay = n
+		case "FROM":
+			if value != "COMPLETION" {
+				return nil, fmt.Errorf("%w: FROM 只支持 COMPLETION", ErrInvalidRecurrence)
+			}
+			rule.FromCompletion = true
+		default:
+			return nil, fmt.Errorf("%w: 不支持的字段 %s", ErrInvalidRecurrence, key)
+		}
+	}
+
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	// 星期按周一到周日排列
+	sort.Slice(rule.ByDay, func(i, j int) bool {
+		return (rule.ByDay[i]+6)%7 < (rule.ByDay[j]+6)%7
+	})
+	return rule, nil
+}
+
+// Validate 检查各字段是否与频率匹配
+func (r *RecurrenceRule) Validate() error {
+	switch r.Freq {
+	case FreqDaily:
+		if len(r.ByDay) > 0 || r.MonthDay != 0 {
+			return fmt.Errorf("%w: FREQ=DAILY 不能设置 BYDAY 或 BYMONTHDAY", ErrInvalidRecurrence)
+		}
+	case FreqWeekly:
+		if len(r.ByDay) == 0 {
+			return fmt.Errorf("%w: FREQ=WEEKLY 需要设置 BYDAY", ErrInvalidRecurrence)
+		}
+	case FreqMonthly:
+		if r.MonthDay == 0 {
+			return fmt.Errorf("%w: FREQ=MONTHLY 需要设置 BYMONTHDAY", ErrInvalidRecurrence)
+		}
+	default:
+		return fmt.Errorf("%w: FREQ 只能是 DAILY、WEEKLY 或 MONTHLY", ErrInvalidRecurrence)
+	}
+	if r.Interval < 1 {
+		return fmt.Errorf("%w: INTERVAL 必须是正整数", ErrInvalidRecurrence)
+	}
+	if r.FromCompletion && r.Freq != FreqDaily {
+		return fmt.Errorf("%w: FROM=COMPLETION 只支持 FREQ=DAILY", ErrInvalidRecurrence)
+	}
+	return nil
+}
+
+// String 返回保存到数据库的规则文本
+func (r *RecurrenceRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			codes[i] = weekdayCodes[day]
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.MonthDay != 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(r.MonthDay))
+	}
+	if r.FromCompletion {
+		parts = append(parts, "FROM=COMPLETION")
+	}
+	return strings.Join(parts, ";")
+}
+
+// Describe 返回规则的中文说明
+func (r *RecurrenceRule) Describe() string {
+	every := func(unit string) string {
+		if r.Interval > 1 {
+			return fmt.Sprintf("每 %d %s", r.Interval, unit)
+		}
+		return "每" + unit
+	}
+
+	switch {
+	case r.FromCompletion:
+		return fmt.Sprintf("完成 %d 天后", r.Interval)
+	case r.Freq == FreqDaily:
+		return every("天")
+	case r.IsWeekdays():
+		return "工作日"
+	case r.Freq == FreqWeekly:
+		names := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			names[i] = weekdayNames[day]
+		}
+		if r.Interval > 1 {
+			return fmt.Sprintf("每 %d 周的周%s", r.Interval, strings.Join(names, "、"))
+		}
+		return "每周" + strings.Join(names, "、")
+	default:
+		return fmt.Sprintf("%s %d 日", every("月"), r.MonthDay)
+	}
+}
+
+// IsWeekdays 判断规则是否为每个工作日重复
+func (r *RecurrenceRule) IsWeekdays() bool {
+	return r.Freq == FreqWeekly && r.Interval == 1 && sameWeekdays(r.ByDay, Weekdays)
+}
+
+// Occurs 判断按日历重复的规则是否在 date 这天重复，start 为系列的第一天
+// FromCompletion 的规则取决于完成时间，总是返回 false
+func (r *RecurrenceRule) Occurs(start, date time.Time) bool {
+	start, date = truncateDay(start), truncateDay(date)
+	if r.FromCompletion || date.Before(start) {
+		return false
+	}
+
+	switch r.Freq {
+	case FreqDaily:
+		days := int(date.Sub(start).Hours() / 24)
+		return days%r.Interval == 0
+	case FreqWeekly:
+		// 按周一开始的周计算间隔
+		weeks := int(weekStart(date).Sub(weekStart(start)).Hours() / 24 / 7)
+		return weeks%r.Interval == 0 && containsWeekday(r.ByDay, date.Weekday())
+	case FreqMonthly:
+		months := (date.Year()-start.Year())*12 + int(date.Month()-start.Month())
+		day := r.MonthDay
+		if last := daysIn(date.Year(), date.Month()); day > last {
+			day = last
+		}
+		return months%r.Interval == 0 && date.Day() == day
+	}
+	return false
+}
+
+// Recurrence 重复任务的系列，保存生成实例时使用的任务内容
+type Recurrence struct {
+	ID          int64          `json:"id"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Priority    int            `json:"priority"`
+	Tags        []string       `json:"tags,omitempty"`
+	Rule        RecurrenceRule `json:"-"`
+	StartDate   string         `json:"start_date"`           // 第一次重复的日期
+	UntilDate   string         `json:"until_date,omitempty"` // 最后一次重复的日期，为空表示一直重复
+	CreatedAt   time.Time      `json:"created_at"`
+}
+
+// Instance 创建系列在 date 这天的任务
+func (r *Recurrence) Instance(date string, now time.Time) *Task {
+	id := r.ID
+	return &Task{
+		Title:        r.Title,
+		Description:  r.Description,
+		Status:       StatusTodo,
+		CreatedAt:    now,
+		Priority:     r.Priority,
+		Date:         date,
+		Tags:         append([]string(nil), r.Tags...),
+		RecurrenceID: &id,
+		Occurrence:   date,
+	}
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// weekStart 返回 t 所在周的周一
+func weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return t.AddDate(0, 0, -offset)
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func containsWeekday(days []time.Weekday, day time.Weekday) bool {
+	for _, d := range days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+func sameWeekdays(a, b []time.Weekday) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, day := range b {
+		if !containsWeekday(a, day) {
+			return false
+		}
+	}
+	return true
+}
+
+func indexOf(list []string, s string) int {
+	for i, item := range list {
+		if item == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -3,9 +3,27 @@ package models
 import "time"
 
 type TaskStats struct {
-	TotalTasks     int
-	CompletedTasks int
-	CompletionRate float64
+	TotalTasks     int     `json:"total_tasks"`
+	CompletedTasks int     `json:"completed_tasks"`
+	TodoTasks      int     `json:"todo_tasks"`
+	DoingTasks     int     `json:"doing_tasks"`
+	DoneTasks      int     `json:"done_tasks"`
+	CancelledTasks int     `json:"cancelled_tasks"`
+	CompletionRate float64 `json:"completion_rate"` // 未取消的任务中已完成的百分比
+
+	// 子任务单独统计，不计入上面的任务数量
+	TotalSubtasks int `json:"total_subtasks"`
+	DoneSubtasks  int `json:"done_subtasks"`
+
+	RolledOverTasks int `json:"rolled_over_tasks"` // 转移过日期的任务数量
+	TotalRollovers  int `json:"total_rollovers"`   // 这些任务转移的总次数
+}
+
+// DateActivity 某一天的任务、番茄钟配置和完成的工作时段数量，用于在日历中标记日期
+type DateActivity struct {
+	Tasks    int `json:"tasks"`
+	Timers   int `json:"timers"`
+	Sessions int `json:"sessions"`
 }
 
 type PomodoroStats struct {
@@ -15,9 +33,10 @@ type PomodoroStats struct {
 }
 
 type PomodoroRecord struct {
-	ID        int64
-	TaskID    int64
-	StartTime time.Time
-	EndTime   time.Time
-	Duration  int64 // 以秒为单位
+	ID          int64
+	TaskID      int64
+	StartTime   time.Time
+	EndTime     time.Time
+	Duration    int64 // 以秒为单位
+	Interrupted bool  // 是否为中途打断的工作时段
 }
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+)
+
+// 标题中的 #标签，标签由字母、数字、下划线和短横线组成
+var tagPattern = regexp.MustCompile(`(^|\s)#([\p{L}\p{N}_-]+)`)
+
+// TagStat 一个标签下任务的完成情况
+type TagStat struct {
+	Tag   string `json:"tag"`
+	Total int    `json:"total"` // 不含已取消的任务
+	Done  int    `json:"done"`
+}
+
+// ParseTags 从快速添加的文本中取出 #标签，返回去掉标签后的标题
+func ParseTags(text string) (string, []string) {
+	var tags []string
+	for _, match := range tagPattern.FindAllStringSubmatch(text, -1) {
+		tags = append(tags, match[2])
+	}
+	title := tagPattern.ReplaceAllString(text, "$1")
+	return strings.Join(strings.Fields(title), " "), NormalizeTags(tags)
+}
+
+// NormalizeTags 去掉空白、开头的 # 和不区分大小写的重复标签
+func NormalizeTags(tags []string) []string {
+	var result []string
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+		key := strings.ToLower(tag)
+		if tag == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// HasTag 判断任务是否带有指定标签，不区分大小写
+func (t *Task) HasTag(tag string) bool {
+	for _, own := range t.Tags {
+		if strings.EqualFold(own, tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,23 +1,164 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 )
 
 type TaskStatus string
 
+// 任务状态，删除任务使用 DeletedAt 标记，与取消是两回事
 const (
-	StatusPending   TaskStatus = "pending"
-	StatusCompleted TaskStatus = "completed"
+	StatusTodo      TaskStatus = "TODO"
+	StatusDoing     TaskStatus = "DOING"
+	StatusDone      TaskStatus = "DONE"
+	StatusCancelled TaskStatus = "CANCELLED"
 )
 
+// TaskStatuses 按看板顺序排列的全部任务状态
+var TaskStatuses = []TaskStatus{StatusTodo, StatusDoing, StatusDone, StatusCancelled}
+
+var (
+	ErrInvalidStatus     = errors.New("无效的任务状态")
+	ErrInvalidTransition = errors.New("不允许的状态变更")
+	ErrInvalidParent     = errors.New("无效的父任务")
+)
+
+// 每个状态允许变更到的状态
+var statusTransitions = map[TaskStatus][]TaskStatus{
+	StatusTodo:      {StatusDoing, StatusDone, StatusCancelled},
+	StatusDoing:     {StatusTodo, StatusDone, StatusCancelled},
+	StatusDone:      {StatusTodo, StatusDoing},
+	StatusCancelled: {StatusTodo},
+}
+
+// 旧版本使用的状态名称
+var legacyStatuses = map[string]TaskStatus{
+	"PENDING":   StatusTodo,
+	"COMPLETED": StatusDone,
+	"UNDO":      StatusCancelled,
+}
+
+// IsValid 判断是否为已定义的状态
+func (s TaskStatus) IsValid() bool {
+	_, ok := statusTransitions[s]
+	return ok
+}
+
+// CanTransitionTo 判断能否从当前状态变更到 next，保持原状态总是允许的
+func (s TaskStatus) CanTransitionTo(next TaskStatus) bool {
+	if s == next {
+		return next.IsValid()
+	}
+	for _, allowed := range statusTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTaskStatus 解析状态名称，不区分大小写并兼容旧的名称
+func ParseTaskStatus(s string) (TaskStatus, error) {
+	name := strings.ToUpper(strings.TrimSpace(s))
+	if status, ok := legacyStatuses[name]; ok {
+		return status, nil
+	}
+	if status := TaskStatus(name); status.IsValid() {
+		return status, nil
+	}
+	return "", fmt.Errorf("%w %q，可选值: TODO、DOING、DONE、CANCELLED", ErrInvalidStatus, s)
+}
+
 type Task struct {
-	ID          int64
-	Title       string
-	Description string
-	Status      TaskStatus
-	CreatedAt   time.Time
-	CompletedAt *time.Time
-	Priority    int
-	Date        string
+	ID          int64      `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Status      TaskStatus `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	Priority    int        `json:"priority"`
+	Date        string     `json:"date"`                // 任务所在的看板日期，为空表示尚未安排
+	DueAt       *time.Time `json:"due_at,omitempty"`    // 截止时间，与看板日期无关
+	ParentID    *int64     `json:"parent_id,omitempty"` // 父任务 ID，为空表示顶层任务
+	Tags        []string   `json:"tags,omitempty"`
+	Position    int        `json:"position"` // 看板列内的手动排序，越小越靠前
+
+	RecurrenceID *int64 `json:"recurrence_id,omitempty"` // 所属的重复系列
+	Occurrence   string `json:"occurrence,omitempty"`    // 重复任务原定的日期，移动到其他日期后保持不变
+
+	OriginalDate  string `json:"original_date,omitempty"`  // 第一次计划的日期，未转移过时为空
+	RolloverCount int    `json:"rollover_count,omitempty"` // 未完成而转到以后日期的次数
+	RolledFrom    *int64 `json:"rolled_from,omitempty"`    // 复制方式转移时原来的任务
+}
+
+// 任务优先级，数值越大越重要
+const (
+	PriorityLow    = 1
+	PriorityMedium = 2
+	PriorityHigh   = 3
+)
+
+// Priorities 可选的优先级，从低到高排列
+var Priorities = []int{PriorityLow, PriorityMedium, PriorityHigh}
+
+// PriorityName 返回优先级的显示名称，超出范围的值按最近的级别显示
+func PriorityName(priority int) string {
+	switch {
+	case priority >= PriorityHigh:
+		return "高"
+	case priority == PriorityMedium:
+		return "中"
+	default:
+		return "低"
+	}
+}
+
+// IsOverdue 返回未完成的任务是否已经超过截止时间
+func (t *Task) IsOverdue(now time.Time) bool {
+	if t.DueAt == nil || t.Status == StatusDone || t.Status == StatusCancelled {
+		return false
+	}
+	return t.DueAt.Before(now)
+}
+
+// InBacklog 返回任务是否尚未安排日期
+func (t *Task) InBacklog() bool {
+	return t.Date == ""
+}
+
+// SubtaskProgress 返回子任务中已完成的数量和未取消的总数
+func SubtaskProgress(subtasks []*Task) (done, total int) {
+	for _, sub := range subtasks {
+		switch sub.Status {
+		case StatusDone:
+			done++
+			total++
+		case StatusCancelled:
+		default:
+			total++
+		}
+	}
+	return done, total
+}
+
+// SetStatus 按允许的变更修改状态，并维护完成时间
+func (t *Task) SetStatus(next TaskStatus, now time.Time) error {
+	if !next.IsValid() {
+		return fmt.Errorf("%w %q", ErrInvalidStatus, next)
+	}
+	if t.Status != "" && !t.Status.CanTransitionTo(next) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, t.Status, next)
+	}
+
+	if next == StatusDone && t.Status != StatusDone {
+		t.CompletedAt = &now
+	} else if next != StatusDone {
+		t.CompletedAt = nil
+	}
+	t.Status = next
+	return nil
 }
@@ -18,3 +18,16 @@ type Timer struct {
 	State     TimerState
 	Task      *Task // 关联的任务
 }
+
+// TimerRuntime 计时器的运行状态，保存后可以在程序重启时恢复
+type TimerRuntime struct {
+	TimerConfigID int64
+	Phase         int
+	State         TimerState
+	Remaining     time.Duration
+	Duration      time.Duration // 当前阶段的总时长
+	Completed     int
+	PhaseStart    time.Time // 当前阶段开始的时间，未开始时为零值
+	TaskID        int64     // 关联的任务，0 表示没有
+	UpdatedAt     time.Time // 保存状态的时间，用于计算程序关闭期间经过的时长
+}
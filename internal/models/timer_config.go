@@ -9,4 +9,9 @@ type TimerConfig struct {
 	BreakDuration time.Duration
 	LongBreak     time.Duration
 	Date          time.Time
-} 
\ No newline at end of file
+
+	// 单独设置的自动开始和长休息间隔，为 nil 时使用全局配置
+	AutoStartBreak    *bool
+	AutoStartPomodoro *bool
+	LongBreakAfter    *int
+}
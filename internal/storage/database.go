@@ -3,117 +3,184 @@ package storage
 import (
 	"TodoList/internal/models"
 	"database/sql"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type Database struct {
-	db *sql.DB
+	db   querier // 执行语句使用的连接，withTx 中为事务
+	conn *sql.DB
+
+	autoCompleteParent *atomic.Bool // 子任务全部完成后是否自动完成父任务，事务中的副本共用同一个设置
+}
+
+// querier 是 *sql.DB 和 *sql.Tx 共有的方法
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
 }
 
-func NewDatabase() (*Database, error) {
-	db, err := sql.Open("sqlite3", "pomodoro.db")
+// NewDatabase 打开 path 处的数据库，目录不存在时自动创建
+func NewDatabase(path string) (*Database, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	// 事务开始时就获取写锁，并发的读写事务排队执行，不会在升级为写事务时失败
+	db, err := sql.Open("sqlite3", path+"?_txlock=immediate")
 	if err != nil {
 		return nil, err
 	}
 
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, err
 	}
 
-	database := &Database{db: db}
-	database.initTables()
+	database := &Database{db: db, conn: db, autoCompleteParent: &atomic.Bool{}}
+	if err := database.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
 	return database, nil
 }
 
-func (d *Database) initTables() error {
-	// 创建任务表
-	_, err := d.db.Exec(`
-        CREATE TABLE IF NOT EXISTS tasks (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            title TEXT NOT NULL,
-            description TEXT,
-            status TEXT NOT NULL,
-            created_at DATETIME NOT NULL,
-            completed_at DATETIME,
-            priority INTEGER NOT NULL,
-            date TEXT NOT NULL DEFAULT CURRENT_DATE
-        )
-    `)
+// Close 关闭数据库连接
+func (d *Database) Close() error {
+	return d.conn.Close()
+}
+
+// withTx 在一个事务中执行 fn，fn 通过 tx 执行的操作在 fn 返回错误时全部回滚。
+// 已经在事务中时直接使用当前事务
+func (d *Database) withTx(fn func(tx *Database) error) error {
+	if _, ok := d.db.(*sql.Tx); ok {
+		return fn(d)
+	}
+
+	tx, err := d.conn.Begin()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	// 检查 date 列是否存在，如果不存在则添加
-	var hasDateColumn bool
-	err = d.db.QueryRow(`
-        SELECT COUNT(*) > 0 
-        FROM pragma_table_info('tasks') 
-        WHERE name = 'date'
-    `).Scan(&hasDateColumn)
-
-	if err != nil {
+	if err := fn(&Database{db: tx, conn: d.conn, autoCompleteParent: d.autoCompleteParent}); err != nil {
 		return err
 	}
+	return tx.Commit()
+}
 
-	if !hasDateColumn {
-		_, err = d.db.Exec(`
-            ALTER TABLE tasks 
-            ADD COLUMN date TEXT NOT NULL DEFAULT CURRENT_DATE
-        `)
-		if err != nil {
+// 任务相关方法
+
+// SaveTask 保存任务，ID 为 0 时新建任务。任务、子任务的日期和标签在同一个事务中保存
+func (d *Database) SaveTask(task *models.Task) error {
+	// 新建失败时不保留已经回滚的 ID
+	id := task.ID
+	err := d.withTx(func(tx *Database) error {
+		if task.ID == 0 {
+			if !task.Status.IsValid() {
+				return fmt.Errorf("%w %q", models.ErrInvalidStatus, task.Status)
+			}
+			return tx.insertTask(task)
+		}
+
+		if err := tx.validateTransition(task); err != nil {
 			return err
 		}
+		return tx.updateTask(task)
+	})
+	if err != nil {
+		task.ID = id
 	}
+	return err
+}
 
-	// 创建番茄钟记录表
-	_, err = d.db.Exec(`
-        CREATE TABLE IF NOT EXISTS pomodoro_records (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            task_id INTEGER,
-            start_time DATETIME NOT NULL,
-            end_time DATETIME NOT NULL,
-            duration INTEGER NOT NULL,
-            FOREIGN KEY(task_id) REFERENCES tasks(id)
-        )
-    `)
-	if err != nil {
-		return err
+// validateTransition 检查任务状态是否有效，以及相对数据库中的状态是否允许变更
+func (d *Database) validateTransition(task *models.Task) error {
+	if !task.Status.IsValid() {
+		return fmt.Errorf("%w %q", models.ErrInvalidStatus, task.Status)
 	}
 
-	// 创建番茄钟配置表
-	_, err = d.db.Exec(`
-        CREATE TABLE IF NOT EXISTS timer_configs (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            name TEXT NOT NULL,
-            work_duration INTEGER NOT NULL,
-            break_duration INTEGER NOT NULL,
-            long_break INTEGER NOT NULL,
-            date TEXT NOT NULL
-        )
-    `)
+	var current models.TaskStatus
+	err := d.db.QueryRow(`SELECT status FROM tasks WHERE id = ? AND deleted_at IS NULL`, task.ID).Scan(&current)
 	if err != nil {
-		fmt.Printf("Error creating timer_configs table: %v\n", err)
 		return err
 	}
+	if !current.CanTransitionTo(task.Status) {
+		return fmt.Errorf("%w: %s -> %s", models.ErrInvalidTransition, current, task.Status)
+	}
 	return nil
 }
 
-// 任务相关方法
-func (d *Database) SaveTask(task *models.Task) error {
-	if task.ID == 0 {
-		return d.insertTask(task)
+// SetAutoCompleteParent 设置子任务全部完成后是否自动将父任务标记为完成
+func (d *Database) SetAutoCompleteParent(enabled bool) {
+	d.autoCompleteParent.Store(enabled)
+}
+
+// checkParent 检查父任务是否存在且本身不是子任务，子任务总是与父任务在同一天
+func (d *Database) checkParent(task *models.Task) error {
+	if task.ParentID == nil {
+		return nil
+	}
+	if *task.ParentID == task.ID {
+		return fmt.Errorf("%w: 任务不能是自己的子任务", models.ErrInvalidParent)
+	}
+
+	parent, err := d.GetTaskByID(*task.ParentID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("%w: 任务 #%d 不存在", models.ErrInvalidParent, *task.ParentID)
+	}
+	if err != nil {
+		return err
+	}
+	if parent.ParentID != nil {
+		return fmt.Errorf("%w: 任务 #%d 本身是子任务", models.ErrInvalidParent, parent.ID)
+	}
+
+	if task.ID != 0 {
+		var children int
+		err := d.db.QueryRow(`SELECT COUNT(*) FROM tasks WHERE parent_id = ? AND deleted_at IS NULL`, task.ID).Scan(&children)
+		if err != nil {
+			return err
+		}
+		if children > 0 {
+			return fmt.Errorf("%w: 任务 #%d 已有子任务", models.ErrInvalidParent, task.ID)
+		}
 	}
-	return d.updateTask(task)
+
+	task.Date = parent.Date
+	return nil
 }
 
 func (d *Database) insertTask(task *models.Task) error {
+	if err := d.checkParent(task); err != nil {
+		return err
+	}
+
+	// 新任务排在同一天所有任务的后面
+	if task.Position == 0 {
+		err := d.db.QueryRow(
+			`SELECT COALESCE(MAX(position), 0) + 1 FROM tasks WHERE date = ? AND deleted_at IS NULL`, task.Date,
+		).Scan(&task.Position)
+		if err != nil {
+			return err
+		}
+	}
+
 	result, err := d.db.Exec(`
-        INSERT INTO tasks (title, description, status, created_at, completed_at, priority, date)
-        VALUES (?, ?, ?, ?, ?, ?, ?)
-    `, task.Title, task.Description, task.Status, task.CreatedAt, task.CompletedAt, task.Priority, task.Date)
+        INSERT INTO tasks (title, description, status, created_at, completed_at, priority, date, due_at, parent_id,
+            recurrence_id, occurrence, original_date, rollover_count, rolled_from, position)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    `, task.Title, task.Description, task.Status, task.CreatedAt, task.CompletedAt, task.Priority, task.Date, task.DueAt, task.ParentID,
+		task.RecurrenceID, sql.NullString{String: task.Occurrence, Valid: task.Occurrence != ""},
+		sql.NullString{String: task.OriginalDate, Valid: task.OriginalDate != ""}, task.RolloverCount, task.RolledFrom, task.Position)
 
 	if err != nil {
 		return err
@@ -124,97 +191,216 @@ func (d *Database) insertTask(task *models.Task) error {
 		return err
 	}
 	task.ID = id
-	return nil
+	return d.saveTags(task)
 }
 
 func (d *Database) updateTask(task *models.Task) error {
+	if err := d.checkParent(task); err != nil {
+		return err
+	}
+
 	_, err := d.db.Exec(`
         UPDATE tasks 
-        SET title = ?, description = ?, status = ?, completed_at = ?, priority = ?, date = ?
+        SET title = ?, description = ?, status = ?, completed_at = ?, priority = ?, date = ?, due_at = ?, parent_id = ?,
+            recurrence_id = ?, occurrence = ?, original_date = ?, rollover_count = ?, rolled_from = ?, position = ?
         WHERE id = ?
-    `, task.Title, task.Description, task.Status, task.CompletedAt, task.Priority, task.Date, task.ID)
-	return err
+    `, task.Title, task.Description, task.Status, task.CompletedAt, task.Priority, task.Date, task.DueAt, task.ParentID,
+		task.RecurrenceID, sql.NullString{String: task.Occurrence, Valid: task.Occurrence != ""},
+		sql.NullString{String: task.OriginalDate, Valid: task.OriginalDate != ""}, task.RolloverCount, task.RolledFrom, task.Position,
+		task.ID)
+	if err != nil {
+		return err
+	}
+
+	// 子任务跟随父任务所在的日期
+	_, err = d.db.Exec(`UPDATE tasks SET date = ? WHERE parent_id = ? AND deleted_at IS NULL`, task.Date, task.ID)
+	if err != nil {
+		return err
+	}
+	if err := d.saveTags(task); err != nil {
+		return err
+	}
+
+	if task.ParentID != nil && d.autoCompleteParent.Load() {
+		return d.completeParent(*task.ParentID)
+	}
+	return nil
+}
+
+// completeParent 在子任务全部完成或取消后将父任务标记为完成，已取消的父任务保持不变
+func (d *Database) completeParent(parentID int64) error {
+	subtasks, err := d.GetSubtasks(parentID)
+	if err != nil {
+		return err
+	}
+	if done, total := models.SubtaskProgress(subtasks); done == 0 || done < total {
+		return nil
+	}
+
+	parent, err := d.GetTaskByID(parentID)
+	if err != nil {
+		return err
+	}
+	if parent.Status == models.StatusDone || !parent.Status.CanTransitionTo(models.StatusDone) {
+		return nil
+	}
+	if err := parent.SetStatus(models.StatusDone, time.Now()); err != nil {
+		return err
+	}
+	return d.updateTask(parent)
+}
+
+// 查询任务时选择的列，与 scanTask 的顺序一致
+const taskColumns = `id, title, description, status, created_at, completed_at, priority, date, due_at, parent_id, recurrence_id, occurrence,
+    original_date, rollover_count, rolled_from, position`
+
+// scanTask 读取一行 taskColumns
+func scanTask(row interface{ Scan(...any) error }) (*models.Task, error) {
+	task := &models.Task{}
+	var dueAt sql.NullTime
+	var parentID, recurrenceID, rolledFrom sql.NullInt64
+	var occurrence, originalDate sql.NullString
+	if err := row.Scan(
+		&task.ID,
+		&task.Title,
+		&task.Description,
+		&task.Status,
+		&task.CreatedAt,
+		&task.CompletedAt,
+		&task.Priority,
+		&task.Date,
+		&dueAt,
+		&parentID,
+		&recurrenceID,
+		&occurrence,
+		&originalDate,
+		&task.RolloverCount,
+		&rolledFrom,
+		&task.Position,
+	); err != nil {
+		return nil, err
+	}
+	if dueAt.Valid {
+		task.DueAt = &dueAt.Time
+	}
+	if parentID.Valid {
+		task.ParentID = &parentID.Int64
+	}
+	if recurrenceID.Valid {
+		task.RecurrenceID = &recurrenceID.Int64
+	}
+	if rolledFrom.Valid {
+		task.RolledFrom = &rolledFrom.Int64
+	}
+	task.Occurrence = occurrence.String
+	task.OriginalDate = originalDate.String
+	return task, nil
 }
 
 // 番茄钟记录相关方法
 func (d *Database) SavePomodoroRecord(record *models.PomodoroRecord) error {
-	_, err := d.db.Exec(`
-        INSERT INTO pomodoro_records (task_id, start_time, end_time, duration)
-        VALUES (?, ?, ?, ?)
-    `, record.TaskID, record.StartTime, record.EndTime, record.Duration)
-	return err
-}
+	// 未关联任务时写入 NULL，避免违反外键约束
+	taskID := sql.NullInt64{Int64: record.TaskID, Valid: record.TaskID != 0}
 
-// 统计相关方法
-type TaskStats struct {
-	TotalTasks     int
-	CompletedTasks int
-	TodoTasks      int
-	DoingTasks     int
-	DoneTasks      int
-	CancelledTasks int
+	result, err := d.db.Exec(`
+        INSERT INTO pomodoro_records (task_id, start_time, end_time, duration, interrupted)
+        VALUES (?, ?, ?, ?, ?)
+    `, taskID, record.StartTime, record.EndTime, record.Duration, record.Interrupted)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	record.ID = id
+	return nil
 }
 
+// 统计相关方法
 type PomodoroStats struct {
-	TotalSessions   int
-	TotalDuration   int // 总时长（秒）
-	TodaySessions   int
-	TodayDuration   int     // 今日时长（秒）
-	AverageDuration float64 // 平均时长（秒）
+	TotalSessions   int     `json:"total_sessions"`
+	TotalDuration   int     `json:"total_duration"` // 总时长（秒）
+	TodaySessions   int     `json:"today_sessions"`
+	TodayDuration   int     `json:"today_duration"`   // 今日时长（秒）
+	AverageDuration float64 `json:"average_duration"` // 平均时长（秒）
 }
 
-func (d *Database) GetTaskStats(startDate, endDate time.Time) (*TaskStats, error) {
-	stats := &TaskStats{}
+// GetTaskStats 统计日期范围内未删除的任务，子任务单独计数
+func (d *Database) GetTaskStats(startDate, endDate time.Time) (*models.TaskStats, error) {
+	stats := &models.TaskStats{}
 
 	query := `
         SELECT 
-            COALESCE(COUNT(*), 0) as total,
-            COALESCE(SUM(CASE WHEN status = 'DONE' THEN 1 ELSE 0 END), 0) as completed,
+            COUNT(*) as total,
             COALESCE(SUM(CASE WHEN status = 'TODO' THEN 1 ELSE 0 END), 0) as todo,
             COALESCE(SUM(CASE WHEN status = 'DOING' THEN 1 ELSE 0 END), 0) as doing,
             COALESCE(SUM(CASE WHEN status = 'DONE' THEN 1 ELSE 0 END), 0) as done,
-            COALESCE(SUM(CASE WHEN status = 'UNDO' THEN 1 ELSE 0 END), 0) as cancelled
-        FROM (
-            SELECT status 
-            FROM tasks 
-            WHERE date BETWEEN date(?) AND date(?)
-            AND status IN ('TODO', 'DOING', 'DONE', 'UNDO')
-        ) t
+            COALESCE(SUM(CASE WHEN status = 'CANCELLED' THEN 1 ELSE 0 END), 0) as cancelled,
+            COALESCE(SUM(CASE WHEN rollover_count > 0 THEN 1 ELSE 0 END), 0) as rolled_over,
+            COALESCE(SUM(rollover_count), 0) as rollovers
+        FROM tasks 
+        WHERE date BETWEEN date(?) AND date(?)
+        AND deleted_at IS NULL
+        AND parent_id IS NULL
     `
 
 	err := d.db.QueryRow(query, startDate.Format("2006-01-02"), endDate.Format("2006-01-02")).Scan(
 		&stats.TotalTasks,
-		&stats.CompletedTasks,
 		&stats.TodoTasks,
 		&stats.DoingTasks,
 		&stats.DoneTasks,
 		&stats.CancelledTasks,
+		&stats.RolledOverTasks,
+		&stats.TotalRollovers,
 	)
+	if err != nil {
+		return nil, err
+	}
 
-	// 如果没有数据，返回全零的���计结果
-	if err == sql.ErrNoRows {
-		return &TaskStats{}, nil
+	err = d.db.QueryRow(`
+        SELECT 
+            COUNT(*),
+            COALESCE(SUM(CASE WHEN status = 'DONE' THEN 1 ELSE 0 END), 0)
+        FROM tasks 
+        WHERE date BETWEEN date(?) AND date(?)
+        AND deleted_at IS NULL
+        AND parent_id IS NOT NULL
+    `, startDate.Format("2006-01-02"), endDate.Format("2006-01-02")).Scan(&stats.TotalSubtasks, &stats.DoneSubtasks)
+	if err != nil {
+		return nil, err
 	}
 
-	return stats, err
+	// 取消的任务不计入完成率
+	stats.CompletedTasks = stats.DoneTasks
+	if active := stats.TotalTasks - stats.CancelledTasks; active > 0 {
+		stats.CompletionRate = float64(stats.DoneTasks) / float64(active) * 100
+	}
+	return stats, nil
 }
 
 func (d *Database) GetPomodoroStats(startDate, endDate time.Time) (*PomodoroStats, error) {
 	stats := &PomodoroStats{}
 	today := time.Now().Truncate(24 * time.Hour)
 
-	// 获取总体统计
+	// 获取总体统计（只统计完整完成的工作时段）
 	err := d.db.QueryRow(`
         SELECT 
             COUNT(*) as sessions,
             COALESCE(SUM(duration), 0) as total_duration
         FROM pomodoro_records
         WHERE start_time BETWEEN ? AND ?
+        AND interrupted = 0
     `, startDate, endDate).Scan(&stats.TotalSessions, &stats.TotalDuration)
 	if err != nil {
 		return nil, err
 	}
 
+	if stats.TotalSessions > 0 {
+		stats.AverageDuration = float64(stats.TotalDuration) / float64(stats.TotalSessions)
+	}
+
 	// 获取今日统计
 	err = d.db.QueryRow(`
         SELECT 
@@ -222,15 +408,46 @@ func (d *Database) GetPomodoroStats(startDate, endDate time.Time) (*PomodoroStat
             COALESCE(SUM(duration), 0) as today_duration
         FROM pomodoro_records
         WHERE start_time >= ?
+        AND interrupted = 0
     `, today).Scan(&stats.TodaySessions, &stats.TodayDuration)
 
 	return stats, err
 }
 
+// GetPomodoroCountsByDate 返回指定日期各任务已完成的番茄钟数量
+func (d *Database) GetPomodoroCountsByDate(date string) (map[int64]int, error) {
+	rows, err := d.db.Query(`
+        SELECT r.task_id, COUNT(*)
+        FROM pomodoro_records r
+        JOIN tasks t ON t.id = r.task_id
+        WHERE t.date = ?
+        AND t.deleted_at IS NULL
+        AND r.interrupted = 0
+        GROUP BY r.task_id
+    `, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[int64]int)
+	for rows.Next() {
+		var taskID int64
+		var count int
+		if err := rows.Scan(&taskID, &count); err != nil {
+			return nil, err
+		}
+		counts[taskID] = count
+	}
+	return counts, rows.Err()
+}
+
+// GetDistinctDates 返回有任务或番茄钟配置的日期，按日期倒序排列
 func (d *Database) GetDistinctDates() ([]string, error) {
 	rows, err := d.db.Query(`
-        SELECT DISTINCT date 
-        FROM timer_configs 
+        SELECT date FROM tasks WHERE deleted_at IS NULL AND date != ''
+        UNION
+        SELECT date FROM timer_configs
         ORDER BY date DESC
     `)
 	if err != nil {
@@ -246,60 +463,290 @@ func (d *Database) GetDistinctDates() ([]string, error) {
 		}
 		dates = append(dates, date)
 	}
-	return dates, nil
+	return dates, rows.Err()
 }
 
-func (d *Database) GetTasksByDate(date string) ([]*models.Task, error) {
-	var tasks []*models.Task
+// GetActiveDates 返回 start 到 end（包含两端）之间每一天的任务、番茄钟配置和工作时段数量，
+// 没有任何记录的日期不在结果中
+func (d *Database) GetActiveDates(start, end time.Time) (map[string]*models.DateActivity, error) {
+	from, to := start.Format("2006-01-02"), end.Format("2006-01-02")
+	result := make(map[string]*models.DateActivity)
+	day := func(date string) *models.DateActivity {
+		if result[date] == nil {
+			result[date] = &models.DateActivity{}
+		}
+		return result[date]
+	}
+
 	rows, err := d.db.Query(`
-        SELECT id, title, description, status, created_at, completed_at, priority, date 
+        SELECT date, COUNT(*), 0 FROM tasks
+        WHERE date BETWEEN ? AND ?
+        AND deleted_at IS NULL
+        AND parent_id IS NULL
+        GROUP BY date
+        UNION ALL
+        SELECT date, 0, COUNT(*) FROM timer_configs
+        WHERE date BETWEEN ? AND ?
+        GROUP BY date
+    `, from, to, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var date string
+		var tasks, timers int
+		if err := rows.Scan(&date, &tasks, &timers); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		day(date).Tasks += tasks
+		day(date).Timers += timers
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 工作时段按开始时间所在的本地日期统计
+	rangeStart := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.Local)
+	rangeEnd := time.Date(end.Year(), end.Month(), end.Day()+1, 0, 0, 0, 0, time.Local)
+	rows, err = d.db.Query(`
+        SELECT start_time FROM pomodoro_records
+        WHERE start_time >= ? AND start_time < ?
+        AND interrupted = 0
+    `, rangeStart.Add(-24*time.Hour), rangeEnd.Add(24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var startTime time.Time
+		if err := rows.Scan(&startTime); err != nil {
+			return nil, err
+		}
+		local := startTime.Local()
+		if local.Before(rangeStart) || !local.Before(rangeEnd) {
+			continue
+		}
+		day(local.Format("2006-01-02")).Sessions++
+	}
+	return result, rows.Err()
+}
+
+func (d *Database) GetTasksByDate(date string) ([]*models.Task, error) {
+	return d.queryTasks(`
+        SELECT `+taskColumns+`
         FROM tasks 
         WHERE date = ?
+        AND deleted_at IS NULL
         ORDER BY priority DESC, created_at DESC
     `, date)
+}
+
+// GetTasksInRange 返回 start 到 end（包含两端）之间的任务，按日期排列
+func (d *Database) GetTasksInRange(start, end string) ([]*models.Task, error) {
+	return d.queryTasks(`
+        SELECT `+taskColumns+`
+        FROM tasks
+        WHERE date BETWEEN ? AND ?
+        AND deleted_at IS NULL
+        ORDER BY date, priority DESC, created_at DESC
+    `, start, end)
+}
+
+// GetBacklogTasks 返回尚未安排日期的任务
+func (d *Database) GetBacklogTasks() ([]*models.Task, error) {
+	return d.queryTasks(`
+        SELECT ` + taskColumns + `
+        FROM tasks
+        WHERE date = ''
+        AND deleted_at IS NULL
+        ORDER BY priority DESC, created_at DESC
+    `)
+}
+
+// GetTaskByID 按 ID 获取任务，不存在或已删除时返回 sql.ErrNoRows
+func (d *Database) GetTaskByID(id int64) (*models.Task, error) {
+	task, err := scanTask(d.db.QueryRow(`
+        SELECT `+taskColumns+`
+        FROM tasks 
+        WHERE id = ? AND deleted_at IS NULL
+    `, id))
+	if err != nil {
+		return nil, err
+	}
+	if err := d.attachTags([]*models.Task{task}); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// queryTasks 执行选择 taskColumns 的查询，并读取各任务的标签
+func (d *Database) queryTasks(query string, args ...any) ([]*models.Task, error) {
+	rows, err := d.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	var tasks []*models.Task
 	for rows.Next() {
-		task := &models.Task{}
-		if err := rows.Scan(
-			&task.ID,
-			&task.Title,
-			&task.Description,
-			&task.Status,
-			&task.CreatedAt,
-			&task.CompletedAt,
-			&task.Priority,
-			&task.Date,
-		); err != nil {
+		task, err := scanTask(rows)
+		if err != nil {
 			return nil, err
 		}
 		tasks = append(tasks, task)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := d.attachTags(tasks); err != nil {
+		return nil, err
+	}
 	return tasks, nil
 }
 
+// saveTags 用 task.Tags 替换任务的标签，不存在的标签会被创建
+func (d *Database) saveTags(task *models.Task) error {
+	task.Tags = models.NormalizeTags(task.Tags)
+	if _, err := d.db.Exec(`DELETE FROM task_tags WHERE task_id = ?`, task.ID); err != nil {
+		return err
+	}
+
+	for _, tag := range task.Tags {
+		if _, err := d.db.Exec(`INSERT OR IGNORE INTO tags (name) VALUES (?)`, tag); err != nil {
+			return err
+		}
+		_, err := d.db.Exec(`
+            INSERT OR IGNORE INTO task_tags (task_id, tag_id)
+            SELECT ?, id FROM tags WHERE name = ?
+        `, task.ID, tag)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// attachTags 读取任务的标签并按名称排序
+func (d *Database) attachTags(tasks []*models.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*models.Task, len(tasks))
+	args := make([]any, len(tasks))
+	for i, task := range tasks {
+		byID[task.ID] = task
+		args[i] = task.ID
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(tasks)), ",")
+
+	rows, err := d.db.Query(`
+        SELECT tt.task_id, tg.name
+        FROM task_tags tt
+        JOIN tags tg ON tg.id = tt.tag_id
+        WHERE tt.task_id IN (`+placeholders+`)
+        ORDER BY tg.name COLLATE NOCASE
+    `, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var taskID int64
+		var name string
+		if err := rows.Scan(&taskID, &name); err != nil {
+			return err
+		}
+		if task, ok := byID[taskID]; ok {
+			task.Tags = append(task.Tags, name)
+		}
+	}
+	return rows.Err()
+}
+
+// GetTagStats 统计日期范围内各标签下顶层任务的完成情况，按任务数量排序
+func (d *Database) GetTagStats(startDate, endDate time.Time) ([]models.TagStat, error) {
+	rows, err := d.db.Query(`
+        SELECT 
+            tg.name,
+            COALESCE(SUM(CASE WHEN t.status != 'CANCELLED' THEN 1 ELSE 0 END), 0) as total,
+            COALESCE(SUM(CASE WHEN t.status = 'DONE' THEN 1 ELSE 0 END), 0) as done
+        FROM task_tags tt
+        JOIN tags tg ON tg.id = tt.tag_id
+        JOIN tasks t ON t.id = tt.task_id
+        WHERE t.date BETWEEN date(?) AND date(?)
+        AND t.deleted_at IS NULL
+        AND t.parent_id IS NULL
+        GROUP BY tg.id
+        ORDER BY total DESC, tg.name COLLATE NOCASE
+    `, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stats []models.TagStat
+	for rows.Next() {
+		var stat models.TagStat
+		if err := rows.Scan(&stat.Tag, &stat.Total, &stat.Done); err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
+
+// GetSubtasks 按创建顺序返回任务的子任务
+func (d *Database) GetSubtasks(parentID int64) ([]*models.Task, error) {
+	return d.queryTasks(`
+        SELECT `+taskColumns+`
+        FROM tasks
+        WHERE parent_id = ?
+        AND deleted_at IS NULL
+        ORDER BY created_at, id
+    `, parentID)
+}
+
+// CreateTask 新建任务并设置 task.ID
 func (d *Database) CreateTask(task *models.Task) error {
-	_, err := d.db.Exec(
-		"INSERT INTO tasks (title, status, created_at, date) VALUES (?, ?, ?, ?)",
-		task.Title, task.Status, task.CreatedAt, task.Date,
-	)
-	return err
+	if !task.Status.IsValid() {
+		return fmt.Errorf("%w %q", models.ErrInvalidStatus, task.Status)
+	}
+	return d.insertTask(task)
 }
 
+// UpdateTask 保存任务的全部字段，状态变更需要符合允许的变更
 func (d *Database) UpdateTask(task *models.Task) error {
-	_, err := d.db.Exec(
-		"UPDATE tasks SET title = ?, status = ?, completed_at = ? WHERE id = ?",
-		task.Title, task.Status, task.CompletedAt, task.ID,
-	)
-	return err
+	return d.withTx(func(tx *Database) error {
+		if err := tx.validateTransition(task); err != nil {
+			return err
+		}
+		return tx.updateTask(task)
+	})
+}
+
+// SetTaskPositions 按 ids 的顺序保存任务的手动排序，第一个任务的位置为 1
+func (d *Database) SetTaskPositions(ids []int64) error {
+	return d.withTx(func(tx *Database) error {
+		for i, id := range ids {
+			if _, err := tx.db.Exec(`UPDATE tasks SET position = ? WHERE id = ?`, i+1, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
-// 添加删除任务的方法
+// DeleteTask 将任务及其子任务标记为已删除，番茄钟记录仍然保留
 func (d *Database) DeleteTask(taskID int64) error {
-	_, err := d.db.Exec("DELETE FROM tasks WHERE id = ?", taskID)
+	_, err := d.db.Exec(
+		"UPDATE tasks SET deleted_at = ? WHERE (id = ? OR parent_id = ?) AND deleted_at IS NULL",
+		time.Now(), taskID, taskID,
+	)
 	return err
 }
 
@@ -310,31 +757,35 @@ func (d *Database) SaveTimerConfig(config *models.TimerConfig) error {
 	// 修复 SQL 语句，确保关键字之间有空格
 	result, err := d.db.Exec(`
         INSERT INTO timer_configs 
-        (name, work_duration, break_duration, long_break, date)
-        VALUES (?, ?, ?, ?, ?)
-    `, config.Name, 
-       int64(config.WorkDuration.Seconds()), 
-       int64(config.BreakDuration.Seconds()), 
-       int64(config.LongBreak.Seconds()), 
-       config.Date.Format("2006-01-02"))
+        (name, work_duration, break_duration, long_break, date, auto_start_break, auto_start_pomodoro, long_break_after)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+    `, config.Name,
+		int64(config.WorkDuration.Seconds()),
+		int64(config.BreakDuration.Seconds()),
+		int64(config.LongBreak.Seconds()),
+		config.Date.Format("2006-01-02"),
+		config.AutoStartBreak,
+		config.AutoStartPomodoro,
+		config.LongBreakAfter)
 
-    if err != nil {
-        fmt.Printf("Error saving timer config: %v\n", err)
-        return err
-    }
+	if err != nil {
+		fmt.Printf("Error saving timer config: %v\n", err)
+		return err
+	}
 
-    id, err := result.LastInsertId()
-    if err != nil {
-        return err
-    }
-    config.ID = id
-    return nil
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	config.ID = id
+	return nil
 }
 
 // 添加获取指定日期配置的方法
 func (d *Database) GetTimerConfigsByDate(date time.Time) ([]*models.TimerConfig, error) {
 	rows, err := d.db.Query(`
-        SELECT id, name, work_duration, break_duration, long_break
+        SELECT id, name, work_duration, break_duration, long_break,
+               auto_start_break, auto_start_pomodoro, long_break_after
         FROM timer_configs
         WHERE date = ?
     `, date.Format("2006-01-02"))
@@ -348,6 +799,8 @@ func (d *Database) GetTimerConfigsByDate(date time.Time) ([]*models.TimerConfig,
 	for rows.Next() {
 		var config models.TimerConfig
 		var workSeconds, breakSeconds, longBreakSeconds int64
+		var autoStartBreak, autoStartPomodoro sql.NullBool
+		var longBreakAfter sql.NullInt64
 
 		err := rows.Scan(
 			&config.ID,
@@ -355,6 +808,9 @@ func (d *Database) GetTimerConfigsByDate(date time.Time) ([]*models.TimerConfig,
 			&workSeconds,
 			&breakSeconds,
 			&longBreakSeconds,
+			&autoStartBreak,
+			&autoStartPomodoro,
+			&longBreakAfter,
 		)
 		if err != nil {
 			return nil, err
@@ -364,6 +820,16 @@ func (d *Database) GetTimerConfigsByDate(date time.Time) ([]*models.TimerConfig,
 		config.BreakDuration = time.Duration(breakSeconds) * time.Second
 		config.LongBreak = time.Duration(longBreakSeconds) * time.Second
 		config.Date = date
+		if autoStartBreak.Valid {
+			config.AutoStartBreak = &autoStartBreak.Bool
+		}
+		if autoStartPomodoro.Valid {
+			config.AutoStartPomodoro = &autoStartPomodoro.Bool
+		}
+		if longBreakAfter.Valid {
+			n := int(longBreakAfter.Int64)
+			config.LongBreakAfter = &n
+		}
 
 		configs = append(configs, &config)
 	}
@@ -371,25 +837,109 @@ func (d *Database) GetTimerConfigsByDate(date time.Time) ([]*models.TimerConfig,
 	return configs, nil
 }
 
+// GetTimerConfigByName 获取指定日期下指定名称的配置，不存在时返回 sql.ErrNoRows
+func (d *Database) GetTimerConfigByName(name string, date time.Time) (*models.TimerConfig, error) {
+	configs, err := d.GetTimerConfigsByDate(date)
+	if err != nil {
+		return nil, err
+	}
+	for _, config := range configs {
+		if config.Name == name {
+			return config, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
 // 添加删除配置的方法
 func (d *Database) DeleteTimerConfig(name string, date time.Time) error {
 	_, err := d.db.Exec(`
+        DELETE FROM timer_states
+        WHERE timer_config_id IN (SELECT id FROM timer_configs WHERE name = ? AND date = ?)
+    `, name, date.Format("2006-01-02"))
+	if err != nil {
+		return err
+	}
+
+	_, err = d.db.Exec(`
         DELETE FROM timer_configs 
         WHERE name = ? AND date = ?
     `, name, date.Format("2006-01-02"))
 	return err
 }
 
-// 添加更新配置的方法
+// UpdateTimerConfig 按 ID 更新配置的名称、时长和单独设置的选项
 func (d *Database) UpdateTimerConfig(config *models.TimerConfig) error {
 	_, err := d.db.Exec(`
         UPDATE timer_configs 
-        SET work_duration = ?, break_duration = ?, long_break = ?
-        WHERE name = ? AND date = ?
-    `, int64(config.WorkDuration.Seconds()),
-       int64(config.BreakDuration.Seconds()),
-       int64(config.LongBreak.Seconds()),
-       config.Name,
-       config.Date.Format("2006-01-02"))
+        SET name = ?, work_duration = ?, break_duration = ?, long_break = ?,
+            auto_start_break = ?, auto_start_pomodoro = ?, long_break_after = ?
+        WHERE id = ?
+    `, config.Name,
+		int64(config.WorkDuration.Seconds()),
+		int64(config.BreakDuration.Seconds()),
+		int64(config.LongBreak.Seconds()),
+		config.AutoStartBreak,
+		config.AutoStartPomodoro,
+		config.LongBreakAfter,
+		config.ID)
+	return err
+}
+
+// SaveTimerState 保存番茄钟的运行状态
+func (d *Database) SaveTimerState(state *models.TimerRuntime) error {
+	taskID := sql.NullInt64{Int64: state.TaskID, Valid: state.TaskID != 0}
+	var phaseStart *time.Time
+	if !state.PhaseStart.IsZero() {
+		phaseStart = &state.PhaseStart
+	}
+
+	_, err := d.db.Exec(`
+        INSERT INTO timer_states (timer_config_id, phase, state, remaining, duration, completed, phase_start, task_id, updated_at)
+        VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+        ON CONFLICT(timer_config_id) DO UPDATE SET
+            phase = excluded.phase,
+            state = excluded.state,
+            remaining = excluded.remaining,
+            duration = excluded.duration,
+            completed = excluded.completed,
+            phase_start = excluded.phase_start,
+            task_id = excluded.task_id,
+            updated_at = excluded.updated_at
+    `, state.TimerConfigID, state.Phase, state.State,
+		int64(state.Remaining.Seconds()), int64(state.Duration.Seconds()),
+		state.Completed, phaseStart, taskID, state.UpdatedAt)
 	return err
 }
+
+// GetTimerState 获取番茄钟保存的运行状态，没有保存过时返回 sql.ErrNoRows
+func (d *Database) GetTimerState(timerConfigID int64) (*models.TimerRuntime, error) {
+	state := &models.TimerRuntime{TimerConfigID: timerConfigID}
+	var remainingSeconds, durationSeconds int64
+	var phaseStart sql.NullTime
+	var taskID sql.NullInt64
+
+	err := d.db.QueryRow(`
+        SELECT phase, state, remaining, duration, completed, phase_start, task_id, updated_at
+        FROM timer_states
+        WHERE timer_config_id = ?
+    `, timerConfigID).Scan(
+		&state.Phase,
+		&state.State,
+		&remainingSeconds,
+		&durationSeconds,
+		&state.Completed,
+		&phaseStart,
+		&taskID,
+		&state.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	state.Remaining = time.Duration(remainingSeconds) * time.Second
+	state.Duration = time.Duration(durationSeconds) * time.Second
+	state.PhaseStart = phaseStart.Time
+	state.TaskID = taskID.Int64
+	return state, nil
+}
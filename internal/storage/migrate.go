@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// ErrSchemaTooNew 数据库版本高于当前程序支持的版本
+var ErrSchemaTooNew = errors.New("数据库版本高于当前程序")
+
+var migrationName = regexp.MustCompile(`^(\d+)_(.+)\.sql$`)
+
+// migration 表示一个编号的升级脚本
+type migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// loadMigrations 读取内嵌的升级脚本并按版本号排序
+func loadMigrations() ([]migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("无效的迁移文件名: %s", entry.Name())
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("迁移版本 %d 重复: %s, %s", version, other, entry.Name())
+		}
+		seen[version] = entry.Name()
+
+		data, err := migrationFiles.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, migration{
+			Version: version,
+			Name:    match[2],
+			SQL:     string(data),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// LatestSchemaVersion 返回当前程序支持的最高数据库版本
+func LatestSchemaVersion() (int, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// SchemaVersion 返回数据库当前的版本
+func (d *Database) SchemaVersion() (int, error) {
+	return schemaVersion(d.db)
+}
+
+// migrate 依次在事务中执行尚未应用的升级脚本
+func (d *Database) migrate() error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	hasMigrationTable, err := tableExists(d.db, "schema_migrations")
+	if err != nil {
+		return err
+	}
+
+	// 旧版本的数据库没有版本表，先补齐旧代码中临时添加的列
+	if !hasMigrationTable {
+		if err := upgradeLegacySchema(d.db); err != nil {
+			return err
+		}
+	}
+
+	_, err = d.db.Exec(`
+        CREATE TABLE IF NOT EXISTS schema_migrations (
+            version INTEGER PRIMARY KEY,
+            name TEXT NOT NULL,
+            applied_at DATETIME NOT NULL
+        )
+    `)
+	if err != nil {
+		return err
+	}
+
+	current, err := schemaVersion(d.db)
+	if err != nil {
+		return err
+	}
+
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+	if current > latest {
+		return fmt.Errorf("%w: 数据库版本 %d，程序支持的最高版本 %d", ErrSchemaTooNew, current, latest)
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		if err := d.applyMigration(m); err != nil {
+			return fmt.Errorf("执行迁移 %04d_%s 失败: %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+func (d *Database) applyMigration(m migration) error {
+	tx, err := d.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(m.SQL); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+        INSERT INTO schema_migrations (version, name, applied_at)
+        VALUES (?, ?, ?)
+    `, m.Version, m.Name, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func schemaVersion(db querier) (int, error) {
+	var version int
+	err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
+
+// upgradeLegacySchema 为引入迁移之前创建的数据库补齐缺失的列
+func upgradeLegacySchema(db querier) error {
+	legacyColumns := []struct {
+		table  string
+		column string
+		ddl    string
+	}{
+		// SQLite 不允许 ADD COLUMN 使用 CURRENT_DATE 作为默认值，改为根据创建时间回填
+		{"tasks", "date", `
+            ALTER TABLE tasks ADD COLUMN date TEXT NOT NULL DEFAULT '';
+            UPDATE tasks SET date = substr(created_at, 1, 10) WHERE date = '';
+        `},
+		{"pomodoro_records", "interrupted", `ALTER TABLE pomodoro_records ADD COLUMN interrupted INTEGER NOT NULL DEFAULT 0`},
+	}
+
+	for _, c := range legacyColumns {
+		exists, err := tableExists(db, c.table)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+
+		hasColumn, err := columnExists(db, c.table, c.column)
+		if err != nil {
+			return err
+		}
+		if hasColumn {
+			continue
+		}
+
+		if _, err := db.Exec(c.ddl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tableExists(db querier, table string) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`
+        SELECT COUNT(*) > 0
+        FROM sqlite_master
+        WHERE type = 'table' AND name = ?
+    `, table).Scan(&exists)
+	return exists, err
+}
+
+func columnExists(db querier, table, column string) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`
+        SELECT COUNT(*) > 0
+        FROM pragma_table_info(?)
+        WHERE name = ?
+    `, table, column).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// copyLegacyDB 将仓库中引入迁移之前的 pomodoro.db 复制到临时目录，返回副本的路径
+func copyLegacyDB(t *testing.T) string {
+	t.Helper()
+	src, err := os.Open(filepath.Join("..", "..", "pomodoro.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	path := filepath.Join(t.TempDir(), "pomodoro.db")
+	dst, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	if _, err := io.Copy(dst, src); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func openTestDB(t *testing.T, path string) *Database {
+	t.Helper()
+	d, err := NewDatabase(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+func countRows(t *testing.T, d *Database, table string) int {
+	t.Helper()
+	var n int
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	path := copyLegacyDB(t)
+	d := openTestDB(t, path)
+
+	latest, err := LatestSchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, err := d.SchemaVersion(); err != nil || version != latest {
+		t.Fatalf("schema version = %d, %v, want %d", version, err, latest)
+	}
+
+	// 旧数据库缺少的列已经补齐
+	for _, c := range []struct{ table, column string }{
+		{"tasks", "date"},
+		{"pomodoro_records", "interrupted"},
+		{"tasks", "position"},
+	} {
+		exists, err := columnExists(d.db, c.table, c.column)
+		if err != nil || !exists {
+			t.Fatalf("column %s.%s exists = %v, %v", c.table, c.column, exists, err)
+		}
+	}
+
+	// 原有的数据保留
+	if got := countRows(t, d, "timer_configs"); got != 6 {
+		t.Fatalf("timer_configs rows = %d, want 6", got)
+	}
+}
+
+func TestMigrateIsIdempotent(t *testing.T) {
+	path := copyLegacyDB(t)
+	d, err := NewDatabase(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	applied := countRows(t, d, "schema_migrations")
+	d.Close()
+
+	// 重新打开不会再次执行迁移
+	d = openTestDB(t, path)
+	if got := countRows(t, d, "schema_migrations"); got != applied {
+		t.Fatalf("schema_migrations rows = %d, want %d", got, applied)
+	}
+	if got := countRows(t, d, "timer_configs"); got != 6 {
+		t.Fatalf("timer_configs rows = %d, want 6", got)
+	}
+}
+
+func TestMigrateRejectsNewerSchema(t *testing.T) {
+	path := copyLegacyDB(t)
+	d, err := NewDatabase(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := LatestSchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = d.db.Exec(`
+        INSERT INTO schema_migrations (version, name, applied_at)
+        VALUES (?, 'future', CURRENT_TIMESTAMP)
+    `, latest+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Close()
+
+	d, err = NewDatabase(path)
+	if err == nil {
+		d.Close()
+		t.Fatal("NewDatabase succeeded, want ErrSchemaTooNew")
+	}
+	if !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("err = %v, want ErrSchemaTooNew", err)
+	}
+}
+
+func TestFailedMigrationRollsBack(t *testing.T) {
+	d := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+	before, err := d.SchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 第二条语句失败时，第一条语句创建的表也要回滚
+	err = d.applyMigration(migration{
+		Version: before + 1,
+		Name:    "broken",
+		SQL: `
+            CREATE TABLE half_applied (id INTEGER PRIMARY KEY);
+            INSERT INTO missing_table VALUES (1);
+        `,
+	})
+	if err == nil {
+		t.Fatal("applyMigration succeeded, want error")
+	}
+
+	if exists, err := tableExists(d.db, "half_applied"); err != nil || exists {
+		t.Fatalf("half_applied exists = %v, %v, want rolled back", exists, err)
+	}
+	if version, err := d.SchemaVersion(); err != nil || version != before {
+		t.Fatalf("schema version = %d, %v, want %d", version, err, before)
+	}
+}
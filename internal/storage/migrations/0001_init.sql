@@ -0,0 +1,30 @@
+-- 初始表结构
+CREATE TABLE IF NOT EXISTS tasks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title TEXT NOT NULL,
+    description TEXT,
+    status TEXT NOT NULL,
+    created_at DATETIME NOT NULL,
+    completed_at DATETIME,
+    priority INTEGER NOT NULL,
+    date TEXT NOT NULL DEFAULT CURRENT_DATE
+);
+
+CREATE TABLE IF NOT EXISTS pomodoro_records (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id INTEGER,
+    start_time DATETIME NOT NULL,
+    end_time DATETIME NOT NULL,
+    duration INTEGER NOT NULL,
+    interrupted INTEGER NOT NULL DEFAULT 0,
+    FOREIGN KEY(task_id) REFERENCES tasks(id)
+);
+
+CREATE TABLE IF NOT EXISTS timer_configs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    work_duration INTEGER NOT NULL,
+    break_duration INTEGER NOT NULL,
+    long_break INTEGER NOT NULL,
+    date TEXT NOT NULL
+);
@@ -0,0 +1,14 @@
+-- 统一任务状态为 TODO、DOING、DONE、CANCELLED，并改用 deleted_at 标记删除
+ALTER TABLE tasks ADD COLUMN deleted_at DATETIME;
+
+UPDATE tasks SET status = upper(trim(status));
+UPDATE tasks SET status = 'TODO' WHERE status = 'PENDING';
+UPDATE tasks SET status = 'DONE' WHERE status = 'COMPLETED';
+UPDATE tasks SET status = 'CANCELLED' WHERE status = 'UNDO';
+UPDATE tasks SET status = 'TODO' WHERE status NOT IN ('TODO', 'DOING', 'DONE', 'CANCELLED');
+
+-- 旧版本完成任务时不一定记录完成时间
+UPDATE tasks SET completed_at = created_at WHERE status = 'DONE' AND completed_at IS NULL;
+UPDATE tasks SET completed_at = NULL WHERE status != 'DONE';
+
+CREATE INDEX IF NOT EXISTS idx_tasks_date ON tasks(date) WHERE deleted_at IS NULL;
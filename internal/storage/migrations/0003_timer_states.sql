@@ -0,0 +1,14 @@
+-- 保存每个番茄钟配置的运行状态，程序重启后恢复
+CREATE TABLE IF NOT EXISTS timer_states (
+    timer_config_id INTEGER PRIMARY KEY,
+    phase INTEGER NOT NULL,
+    state INTEGER NOT NULL,
+    remaining INTEGER NOT NULL,
+    duration INTEGER NOT NULL,
+    completed INTEGER NOT NULL DEFAULT 0,
+    phase_start DATETIME,
+    task_id INTEGER,
+    updated_at DATETIME NOT NULL,
+    FOREIGN KEY(timer_config_id) REFERENCES timer_configs(id) ON DELETE CASCADE,
+    FOREIGN KEY(task_id) REFERENCES tasks(id)
+);
@@ -0,0 +1,4 @@
+-- 每个番茄钟可以单独设置自动开始和长休息间隔，为 NULL 时使用全局配置
+ALTER TABLE timer_configs ADD COLUMN auto_start_break INTEGER;
+ALTER TABLE timer_configs ADD COLUMN auto_start_pomodoro INTEGER;
+ALTER TABLE timer_configs ADD COLUMN long_break_after INTEGER;
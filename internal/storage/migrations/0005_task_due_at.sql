@@ -0,0 +1,2 @@
+-- 任务的截止时间，与任务所在的看板日期分开保存
+ALTER TABLE tasks ADD COLUMN due_at DATETIME;
@@ -0,0 +1,3 @@
+-- 子任务通过 parent_id 关联到父任务，只支持一层
+ALTER TABLE tasks ADD COLUMN parent_id INTEGER REFERENCES tasks(id);
+CREATE INDEX IF NOT EXISTS idx_tasks_parent_id ON tasks(parent_id);
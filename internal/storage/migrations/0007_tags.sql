@@ -0,0 +1,15 @@
+-- 标签与任务多对多关联，标签名称不区分大小写
+CREATE TABLE IF NOT EXISTS tags (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE COLLATE NOCASE
+);
+
+CREATE TABLE IF NOT EXISTS task_tags (
+    task_id INTEGER NOT NULL,
+    tag_id INTEGER NOT NULL,
+    PRIMARY KEY (task_id, tag_id),
+    FOREIGN KEY(task_id) REFERENCES tasks(id),
+    FOREIGN KEY(tag_id) REFERENCES tags(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_tags_tag_id ON task_tags(tag_id);
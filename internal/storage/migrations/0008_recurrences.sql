@@ -0,0 +1,19 @@
+-- 重复任务的系列，打开某一天时按规则生成当天的任务
+CREATE TABLE IF NOT EXISTS recurrences (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    priority INTEGER NOT NULL,
+    tags TEXT NOT NULL DEFAULT '', -- 以空格分隔的标签
+    rule TEXT NOT NULL,
+    start_date TEXT NOT NULL,
+    until_date TEXT,
+    created_at DATETIME NOT NULL,
+    deleted_at DATETIME
+);
+
+ALTER TABLE tasks ADD COLUMN recurrence_id INTEGER REFERENCES recurrences(id);
+ALTER TABLE tasks ADD COLUMN occurrence TEXT;
+
+-- 每个系列每天只生成一次，已删除的任务也会保留，避免再次生成
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_occurrence ON tasks(recurrence_id, occurrence);
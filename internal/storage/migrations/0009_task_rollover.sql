@@ -0,0 +1,5 @@
+-- 未完成的任务转到以后的日期时记录最初的日期和转移次数
+-- 复制方式转移时新任务通过 rolled_from 关联到原来的任务
+ALTER TABLE tasks ADD COLUMN original_date TEXT;
+ALTER TABLE tasks ADD COLUMN rollover_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tasks ADD COLUMN rolled_from INTEGER REFERENCES tasks(id);
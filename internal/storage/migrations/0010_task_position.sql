@@ -0,0 +1,2 @@
+-- 看板列内的手动排序，越小越靠前
+ALTER TABLE tasks ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
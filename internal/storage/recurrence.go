@@ -0,0 +1,304 @@
+package storage
+
+import (
+	"TodoList/internal/models"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 查询重复系列时选择的列，与 scanRecurrence 的顺序一致
+const recurrenceColumns = `id, title, description, priority, tags, rule, start_date, until_date, created_at`
+
+// scanRecurrence 读取一行 recurrenceColumns
+func scanRecurrence(row interface{ Scan(...any) error }) (*models.Recurrence, error) {
+	r := &models.Recurrence{}
+	var tags, rule string
+	var until sql.NullString
+	if err := row.Scan(
+		&r.ID,
+		&r.Title,
+		&r.Description,
+		&r.Priority,
+		&tags,
+		&rule,
+		&r.StartDate,
+		&until,
+		&r.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	parsed, err := models.ParseRecurrenceRule(rule)
+	if err != nil {
+		return nil, fmt.Errorf("重复系列 #%d: %w", r.ID, err)
+	}
+	r.Rule = *parsed
+	r.Tags = strings.Fields(tags)
+	r.UntilDate = until.String
+	return r, nil
+}
+
+// GetRecurrence 按 ID 获取重复系列，不存在或已删除时返回 sql.ErrNoRows
+func (d *Database) GetRecurrence(id int64) (*models.Recurrence, error) {
+	return scanRecurrence(d.db.QueryRow(`
+        SELECT `+recurrenceColumns+`
+        FROM recurrences
+        WHERE id = ? AND deleted_at IS NULL
+    `, id))
+}
+
+// CreateRecurrence 以 task 的内容创建重复系列，task 作为系列的第一次重复
+// 系列从 task.Occurrence 开始，为空时从 task.Date 开始。创建系列和保存任务在同一个事务中
+func (d *Database) CreateRecurrence(task *models.Task, rule *models.RecurrenceRule) (*models.Recurrence, error) {
+	if task.ParentID != nil {
+		return nil, fmt.Errorf("%w: 子任务不能重复", models.ErrInvalidRecurrence)
+	}
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	start := task.Occurrence
+	if start == "" {
+		start = task.Date
+	}
+	if start == "" {
+		return nil, fmt.Errorf("%w: 尚未安排日期的任务不能重复", models.ErrInvalidRecurrence)
+	}
+	r := &models.Recurrence{
+		Title:       task.Title,
+		Description: task.Description,
+		Priority:    task.Priority,
+		Tags:        models.NormalizeTags(task.Tags),
+		Rule:        *rule,
+		StartDate:   start,
+		CreatedAt:   time.Now(),
+	}
+
+	// 保存失败时恢复任务原来的系列
+	recurrenceID, occurrence := task.RecurrenceID, task.Occurrence
+	err := d.withTx(func(tx *Database) error {
+		result, err := tx.db.Exec(`
+            INSERT INTO recurrences (title, description, priority, tags, rule, start_date, created_at)
+            VALUES (?, ?, ?, ?, ?, ?, ?)
+        `, r.Title, r.Description, r.Priority, strings.Join(r.Tags, " "), r.Rule.String(), r.StartDate, r.CreatedAt)
+		if err != nil {
+			return err
+		}
+		if r.ID, err = result.LastInsertId(); err != nil {
+			return err
+		}
+
+		task.RecurrenceID = &r.ID
+		task.Occurrence = start
+		return tx.SaveTask(task)
+	})
+	if err != nil {
+		task.RecurrenceID, task.Occurrence = recurrenceID, occurrence
+		return nil, err
+	}
+	return r, nil
+}
+
+// UpdateFutureOccurrences 将 task 的修改应用到它和以后的所有重复：
+// 旧系列在 task 的前一天结束，以后尚未完成的任务被删除，再以 task 的内容和 rule 创建新系列。
+// rule 为 nil 时停止重复，task 成为普通任务。所有修改在同一个事务中，失败时都不生效
+func (d *Database) UpdateFutureOccurrences(task *models.Task, rule *models.RecurrenceRule) error {
+	if task.RecurrenceID == nil {
+		return fmt.Errorf("%w: 任务 #%d 不是重复任务", models.ErrInvalidRecurrence, task.ID)
+	}
+	if rule != nil {
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+	}
+
+	occurrence, err := time.Parse("2006-01-02", task.Occurrence)
+	if err != nil {
+		return err
+	}
+
+	// 失败时恢复任务原来的系列
+	recurrenceID, occurrenceDate := task.RecurrenceID, task.Occurrence
+	err = d.withTx(func(tx *Database) error {
+		old, err := tx.GetRecurrence(*task.RecurrenceID)
+		if err != nil {
+			return err
+		}
+
+		// 旧系列到前一天结束，没有剩下的日期时直接删除
+		until := occurrence.AddDate(0, 0, -1).Format("2006-01-02")
+		if until < old.StartDate {
+			_, err = tx.db.Exec(`UPDATE recurrences SET deleted_at = ? WHERE id = ?`, time.Now(), old.ID)
+		} else {
+			_, err = tx.db.Exec(`UPDATE recurrences SET until_date = ? WHERE id = ?`, until, old.ID)
+		}
+		if err != nil {
+			return err
+		}
+
+		// 以后已经生成但尚未完成的任务按新系列重新生成
+		_, err = tx.db.Exec(`
+            UPDATE tasks SET deleted_at = ?
+            WHERE recurrence_id = ? AND occurrence > ?
+            AND deleted_at IS NULL
+            AND status IN ('TODO', 'DOING')
+        `, time.Now(), old.ID, task.Occurrence)
+		if err != nil {
+			return err
+		}
+
+		if rule == nil {
+			task.RecurrenceID = nil
+			task.Occurrence = ""
+			return tx.SaveTask(task)
+		}
+		_, err = tx.CreateRecurrence(task, rule)
+		return err
+	})
+	if err != nil {
+		task.RecurrenceID, task.Occurrence = recurrenceID, occurrenceDate
+	}
+	return err
+}
+
+// SaveRecurringTask 在同一个事务中保存任务和它的重复规则：普通任务提供 rule 时以它创建系列；
+// 重复任务在 future 为 true 时将修改和 rule 应用到以后所有的重复，rule 为 nil 时停止重复；
+// 其他情况只保存任务本身。失败时任务和系列都不修改
+func (d *Database) SaveRecurringTask(task *models.Task, rule *models.RecurrenceRule, future bool) error {
+	id := task.ID
+	err := d.withTx(func(tx *Database) error {
+		switch {
+		case task.RecurrenceID == nil && rule != nil:
+			_, err := tx.CreateRecurrence(task, rule)
+			return err
+		case task.RecurrenceID != nil && future:
+			return tx.UpdateFutureOccurrences(task, rule)
+		default:
+			return tx.SaveTask(task)
+		}
+	})
+	if err != nil {
+		task.ID = id
+	}
+	return err
+}
+
+// MaterializeRecurrences 生成重复系列在 date 这天的任务，已经生成过的（包括已删除的）不会再次生成。
+// 界面、本地接口和周视图可能同时调用，检查和生成在同一个事务中进行
+func (d *Database) MaterializeRecurrences(date string) error {
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return err
+	}
+	return d.withTx(func(tx *Database) error {
+		return tx.materializeRecurrences(date, day)
+	})
+}
+
+// materializeRecurrences 执行 MaterializeRecurrences 的生成，由调用方负责事务
+func (d *Database) materializeRecurrences(date string, day time.Time) error {
+	rows, err := d.db.Query(`
+        SELECT `+recurrenceColumns+`
+        FROM recurrences
+        WHERE deleted_at IS NULL
+        AND start_date <= ?
+        AND (until_date IS NULL OR until_date >= ?)
+    `, date, date)
+	if err != nil {
+		return err
+	}
+	var recurrences []*models.Recurrence
+	for rows.Next() {
+		r, err := scanRecurrence(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		recurrences = append(recurrences, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, r := range recurrences {
+		due, err := d.recurrenceDue(r, day)
+		if err != nil {
+			return err
+		}
+		if !due {
+			continue
+		}
+
+		var exists int
+		err = d.db.QueryRow(
+			`SELECT COUNT(*) FROM tasks WHERE recurrence_id = ? AND occurrence = ?`, r.ID, date,
+		).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if exists > 0 {
+			continue
+		}
+
+		if err := d.insertTask(r.Instance(date, now)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recurrenceDue 判断系列在 day 这天是否需要生成任务
+// 完成后重复的系列在上一次完成 Interval 天后那天生成，到期日已经过去时在今天生成；
+// 还有未完成的任务时不生成，也不会提前生成今天以后的任务
+func (d *Database) recurrenceDue(r *models.Recurrence, day time.Time) (bool, error) {
+	start, err := time.Parse("2006-01-02", r.StartDate)
+	if err != nil {
+		return false, err
+	}
+	if !r.Rule.FromCompletion {
+		return r.Rule.Occurs(start, day), nil
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if day.After(today) {
+		return false, nil
+	}
+
+	var open int
+	err = d.db.QueryRow(`
+        SELECT COUNT(*) FROM tasks
+        WHERE recurrence_id = ?
+        AND deleted_at IS NULL
+        AND status IN ('TODO', 'DOING')
+    `, r.ID).Scan(&open)
+	if err != nil || open > 0 {
+		return false, err
+	}
+
+	next := start
+	var completedAt time.Time
+	err = d.db.QueryRow(`
+        SELECT completed_at FROM tasks
+        WHERE recurrence_id = ?
+        AND deleted_at IS NULL
+        AND status = 'DONE'
+        AND completed_at IS NOT NULL
+        ORDER BY completed_at DESC
+        LIMIT 1
+    `, r.ID).Scan(&completedAt)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		return false, err
+	default:
+		local := completedAt.Local()
+		next = time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, r.Rule.Interval)
+	}
+	return day.Equal(next) || (day.Equal(today) && next.Before(today)), nil
+}
@@ -0,0 +1,228 @@
+package storage
+
+import (
+	"TodoList/internal/models"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// createSeries 在 date 这天创建任务，并以 rule 创建重复系列
+func createSeries(t *testing.T, d *Database, date, rule string) *models.Task {
+	t.Helper()
+	parsed, err := models.ParseRecurrenceRule(rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := &models.Task{Title: "浇花", Status: models.StatusTodo, Priority: models.PriorityLow, Date: date, CreatedAt: time.Now()}
+	if _, err := d.CreateRecurrence(task, parsed); err != nil {
+		t.Fatal(err)
+	}
+	return task
+}
+
+// seriesTasks 返回系列中未删除的任务数
+func seriesTasks(t *testing.T, d *Database, id int64) int {
+	t.Helper()
+	var n int
+	err := d.db.QueryRow(`SELECT COUNT(*) FROM tasks WHERE recurrence_id = ? AND deleted_at IS NULL`, id).Scan(&n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestFromCompletionDueDays(t *testing.T) {
+	d := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+	now := time.Now()
+	date := func(days int) string { return now.AddDate(0, 0, days).Format("2006-01-02") }
+
+	// 十天前创建并完成，两天后（八天前）到期
+	task := createSeries(t, d, date(-10), "FREQ=DAILY;INTERVAL=2;FROM=COMPLETION")
+	completed := now.AddDate(0, 0, -10)
+	task.Status = models.StatusDone
+	task.CompletedAt = &completed
+	if err := d.SaveTask(task); err != nil {
+		t.Fatal(err)
+	}
+	r, err := d.GetRecurrence(*task.RecurrenceID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		days int
+		want bool
+	}{
+		{-9, false}, // 还没到期
+		{-8, true},  // 正好到期
+		{-7, false}, // 到期以后的其他日期不再生成
+		{0, true},   // 已经过期时在今天生成
+		{1, false},  // 不提前生成以后的任务
+		{3, false},
+	} {
+		day, err := time.Parse("2006-01-02", date(tt.days))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := d.recurrenceDue(r, day); err != nil || got != tt.want {
+			t.Errorf("due on day %+d = %v, %v, want %v", tt.days, got, err, tt.want)
+		}
+	}
+
+	// 浏览以后的日期不会生成任务，今天只生成一个
+	for _, days := range []int{1, 5, 0, 0} {
+		if err := d.MaterializeRecurrences(date(days)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := seriesTasks(t, d, r.ID); got != 2 {
+		t.Fatalf("series tasks = %d, want 2", got)
+	}
+	if tasks, err := d.GetTasksByDate(date(0)); err != nil || len(tasks) != 1 || tasks[0].Occurrence != date(0) {
+		t.Fatalf("today tasks = %+v, %v, want one occurrence today", tasks, err)
+	}
+}
+
+func TestUpdateFutureOccurrences(t *testing.T) {
+	d := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+	first := createSeries(t, d, "2024-01-01", "FREQ=DAILY")
+	oldID := *first.RecurrenceID
+	for _, date := range []string{"2024-01-02", "2024-01-03", "2024-01-04"} {
+		if err := d.MaterializeRecurrences(date); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tasks, err := d.GetTasksByDate("2024-01-02")
+	if err != nil || len(tasks) != 1 {
+		t.Fatalf("tasks = %+v, %v", tasks, err)
+	}
+	task := tasks[0]
+	task.Title = "浇水"
+	rule, err := models.ParseRecurrenceRule("FREQ=DAILY;INTERVAL=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.UpdateFutureOccurrences(task, rule); err != nil {
+		t.Fatal(err)
+	}
+
+	// 旧系列在前一天结束，以后的任务属于新系列
+	old, err := d.GetRecurrence(oldID)
+	if err != nil || old.UntilDate != "2024-01-01" {
+		t.Fatalf("old series = %+v, %v, want until 2024-01-01", old, err)
+	}
+	if got := seriesTasks(t, d, oldID); got != 1 {
+		t.Fatalf("old series tasks = %d, want 1", got)
+	}
+	if *task.RecurrenceID == oldID || seriesTasks(t, d, *task.RecurrenceID) != 1 {
+		t.Fatalf("task series = %d, want a new series with the edited task", *task.RecurrenceID)
+	}
+	saved, err := d.GetTaskByID(task.ID)
+	if err != nil || saved.Title != "浇水" || *saved.RecurrenceID != *task.RecurrenceID {
+		t.Fatalf("saved task = %+v, %v", saved, err)
+	}
+}
+
+func TestUpdateFutureOccurrencesRollsBack(t *testing.T) {
+	d := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+	first := createSeries(t, d, "2024-01-01", "FREQ=DAILY")
+	id := *first.RecurrenceID
+	for _, date := range []string{"2024-01-02", "2024-01-03"} {
+		if err := d.MaterializeRecurrences(date); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 最后保存任务时失败，之前结束旧系列和删除以后的任务都要回滚
+	tasks, err := d.GetTasksByDate("2024-01-02")
+	if err != nil || len(tasks) != 1 {
+		t.Fatalf("tasks = %+v, %v", tasks, err)
+	}
+	task := tasks[0]
+	task.Status = "BROKEN"
+	err = d.UpdateFutureOccurrences(task, nil)
+	if !errors.Is(err, models.ErrInvalidStatus) {
+		t.Fatalf("err = %v, want ErrInvalidStatus", err)
+	}
+
+	if task.RecurrenceID == nil || *task.RecurrenceID != id || task.Occurrence != "2024-01-02" {
+		t.Fatalf("task series = %v %q, want restored", task.RecurrenceID, task.Occurrence)
+	}
+	if r, err := d.GetRecurrence(id); err != nil || r.UntilDate != "" {
+		t.Fatalf("series = %+v, %v, want unchanged", r, err)
+	}
+	if got := seriesTasks(t, d, id); got != 3 {
+		t.Fatalf("series tasks = %d, want 3", got)
+	}
+}
+
+func TestMaterializeRecurrencesConcurrently(t *testing.T) {
+	d := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+	first := createSeries(t, d, "2024-01-01", "FREQ=DAILY")
+	dates := []string{"2024-01-02", "2024-01-03", "2024-01-04", "2024-01-05", "2024-01-06", "2024-01-07", "2024-01-08"}
+
+	// 界面刷新、本地接口和周视图同时生成同一周的任务
+	var wg sync.WaitGroup
+	errs := make(chan error, 8*len(dates))
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, date := range dates {
+				if err := d.MaterializeRecurrences(date); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	if got := seriesTasks(t, d, *first.RecurrenceID); got != 1+len(dates) {
+		t.Fatalf("series tasks = %d, want %d", got, 1+len(dates))
+	}
+}
+
+func TestSaveRecurringTaskRollsBack(t *testing.T) {
+	d := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+	rule, err := models.ParseRecurrenceRule("FREQ=DAILY")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 创建系列失败时新任务也不保存，重试不会留下重复的任务
+	_, err = d.db.Exec(`
+        CREATE TRIGGER fail_recurrence BEFORE INSERT ON recurrences
+        BEGIN SELECT RAISE(ABORT, 'recurrence failed'); END
+    `)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := &models.Task{Title: "浇花", Status: models.StatusTodo, Priority: models.PriorityLow, Date: "2024-01-01", CreatedAt: time.Now()}
+	if err := d.SaveRecurringTask(task, rule, false); err == nil {
+		t.Fatal("SaveRecurringTask succeeded, want error")
+	}
+	if task.ID != 0 || task.RecurrenceID != nil {
+		t.Fatalf("task = %+v, want unsaved", task)
+	}
+	if got := countRows(t, d, "tasks"); got != 0 {
+		t.Fatalf("tasks = %d, want 0", got)
+	}
+
+	if _, err := d.db.Exec(`DROP TRIGGER fail_recurrence`); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SaveRecurringTask(task, rule, false); err != nil {
+		t.Fatal(err)
+	}
+	if got := countRows(t, d, "tasks"); got != 1 || task.RecurrenceID == nil {
+		t.Fatalf("tasks = %d, series = %v, want one recurring task", got, task.RecurrenceID)
+	}
+}
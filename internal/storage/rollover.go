@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"TodoList/internal/models"
+	"time"
+)
+
+// RolloverTasks 将 date 以前未完成（待办和进行中）的任务转到 date，返回转移的任务数量。
+// asCopy 为 false 时修改任务的日期，为 true 时在 date 创建副本，原来的任务保留在原来的日期。
+// 子任务随父任务一起转移；按日历重复的任务每天都会生成，不会转移；尚未安排日期的任务不转移
+func (d *Database) RolloverTasks(date string, asCopy bool) (int, error) {
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return 0, err
+	}
+
+	// 全部转移在同一个事务中，中途失败时都不生效，复制方式下重试也不会产生重复的副本
+	var count int
+	err := d.withTx(func(tx *Database) error {
+		n, err := tx.rolloverTasks(date, asCopy)
+		count = n
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// rolloverTasks 执行 RolloverTasks 的转移，由调用方负责事务
+func (d *Database) rolloverTasks(date string, asCopy bool) (int, error) {
+	// 完成后重复的任务在完成前不会生成下一次，移动方式下可以转移；
+	// 复制方式下已经复制过的任务由副本继续转移
+	tasks, err := d.queryTasks(`
+        SELECT `+taskColumns+`
+        FROM tasks
+        WHERE date < ? AND date != ''
+        AND deleted_at IS NULL
+        AND parent_id IS NULL
+        AND status IN ('TODO', 'DOING')
+        AND (
+            recurrence_id IS NULL
+            OR (? = 0 AND recurrence_id IN (
+                SELECT id FROM recurrences WHERE rule LIKE '%FROM=COMPLETION%'
+            ))
+        )
+        AND (? = 0 OR NOT EXISTS (
+            SELECT 1 FROM tasks AS c
+            WHERE c.rolled_from = tasks.id AND c.deleted_at IS NULL
+        ))
+        ORDER BY date, created_at, id
+    `, date, asCopy, asCopy)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, task := range tasks {
+		if asCopy {
+			err = d.copyTask(task, date)
+		} else {
+			if task.OriginalDate == "" {
+				task.OriginalDate = task.Date
+			}
+			task.RolloverCount++
+			task.Date = date
+			err = d.updateTask(task)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(tasks), nil
+}
+
+// copyTask 在 date 创建 task 的副本，未完成的子任务一起复制
+func (d *Database) copyTask(task *models.Task, date string) error {
+	subtasks, err := d.GetSubtasks(task.ID)
+	if err != nil {
+		return err
+	}
+
+	originalID := task.ID
+	original := task.OriginalDate
+	if original == "" {
+		original = task.Date
+	}
+	now := time.Now()
+	rolled := &models.Task{
+		Title:         task.Title,
+		Description:   task.Description,
+		Status:        task.Status,
+		CreatedAt:     now,
+		Priority:      task.Priority,
+		Date:          date,
+		DueAt:         task.DueAt,
+		Tags:          task.Tags,
+		OriginalDate:  original,
+		RolloverCount: task.RolloverCount + 1,
+		RolledFrom:    &originalID,
+	}
+	if err := d.insertTask(rolled); err != nil {
+		return err
+	}
+
+	for _, sub := range subtasks {
+		if sub.Status != models.StatusTodo && sub.Status != models.StatusDoing {
+			continue
+		}
+		subID := sub.ID
+		err := d.insertTask(&models.Task{
+			Title:       sub.Title,
+			Description: sub.Description,
+			Status:      sub.Status,
+			CreatedAt:   now,
+			Priority:    sub.Priority,
+			Date:        date,
+			DueAt:       sub.DueAt,
+			ParentID:    &rolled.ID,
+			Tags:        sub.Tags,
+			RolledFrom:  &subID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetMostRolledOver 返回日期范围内转移次数最多的任务，只包含转移过的任务
+func (d *Database) GetMostRolledOver(startDate, endDate time.Time, limit int) ([]*models.Task, error) {
+	return d.queryTasks(`
+        SELECT `+taskColumns+`
+        FROM tasks
+        WHERE date BETWEEN date(?) AND date(?)
+        AND deleted_at IS NULL
+        AND rollover_count > 0
+        ORDER BY rollover_count DESC, date, id
+        LIMIT ?
+    `, startDate.Format("2006-01-02"), endDate.Format("2006-01-02"), limit)
+}
@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"TodoList/internal/models"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRolloverCopyRollsBackAndRetries(t *testing.T) {
+	d := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+	for _, title := range []string{"写周报", "失败"} {
+		task := &models.Task{Title: title, Status: models.StatusTodo, Priority: models.PriorityLow, Date: "2024-01-01", CreatedAt: time.Now()}
+		if err := d.SaveTask(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 第二个任务的副本写入失败时，第一个任务的副本也不保留
+	_, err := d.db.Exec(`
+        CREATE TRIGGER fail_copy BEFORE INSERT ON tasks
+        WHEN NEW.title = '失败'
+        BEGIN SELECT RAISE(ABORT, 'copy failed'); END
+    `)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.RolloverTasks("2024-01-02", true); err == nil {
+		t.Fatal("RolloverTasks succeeded, want error")
+	}
+	if tasks, err := d.GetTasksByDate("2024-01-02"); err != nil || len(tasks) != 0 {
+		t.Fatalf("copies after failure = %d, %v, want 0", len(tasks), err)
+	}
+
+	// 重试时每个任务只复制一次
+	if _, err := d.db.Exec(`DROP TRIGGER fail_copy`); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []int{2, 0} {
+		if n, err := d.RolloverTasks("2024-01-02", true); err != nil || n != want {
+			t.Fatalf("rolled over = %d, %v, want %d", n, err, want)
+		}
+	}
+	if tasks, err := d.GetTasksByDate("2024-01-02"); err != nil || len(tasks) != 2 {
+		t.Fatalf("copies = %d, %v, want 2", len(tasks), err)
+	}
+}
+
+// addTask 保存 date 这天的任务，parent 不为空时作为它的子任务
+func addTask(t *testing.T, d *Database, title, date string, status models.TaskStatus, parent *models.Task) *models.Task {
+	t.Helper()
+	task := &models.Task{Title: title, Status: status, Priority: models.PriorityLow, Date: date, CreatedAt: time.Now()}
+	if parent != nil {
+		task.ParentID = &parent.ID
+	}
+	if err := d.SaveTask(task); err != nil {
+		t.Fatal(err)
+	}
+	return task
+}
+
+func getTask(t *testing.T, d *Database, id int64) *models.Task {
+	t.Helper()
+	task, err := d.GetTaskByID(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return task
+}
+
+func TestRolloverMove(t *testing.T) {
+	d := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+	todo := addTask(t, d, "写周报", "2024-01-01", models.StatusTodo, nil)
+	sub := addTask(t, d, "整理数据", "", models.StatusTodo, todo)
+	doing := addTask(t, d, "读书", "2024-01-01", models.StatusDoing, nil)
+	done := addTask(t, d, "买菜", "2024-01-01", models.StatusDone, nil)
+	future := addTask(t, d, "开会", "2024-01-03", models.StatusTodo, nil)
+	backlog := addTask(t, d, "学吉他", "", models.StatusTodo, nil)
+	daily := createSeries(t, d, "2024-01-01", "FREQ=DAILY")
+	fromCompletion := createSeries(t, d, "2024-01-01", "FREQ=DAILY;FROM=COMPLETION")
+
+	if _, err := d.RolloverTasks("01/02/2024", false); err == nil {
+		t.Fatal("RolloverTasks accepted an invalid date")
+	}
+
+	// 未完成的顶层任务和完成后重复的任务转到新日期，子任务跟随父任务
+	if n, err := d.RolloverTasks("2024-01-02", false); err != nil || n != 3 {
+		t.Fatalf("rolled over = %d, %v, want 3", n, err)
+	}
+	for _, task := range []*models.Task{todo, sub, doing, fromCompletion} {
+		if got := getTask(t, d, task.ID); got.Date != "2024-01-02" {
+			t.Errorf("%s date = %s, want 2024-01-02", task.Title, got.Date)
+		}
+	}
+	if got := getTask(t, d, todo.ID); got.OriginalDate != "2024-01-01" || got.RolloverCount != 1 || got.RolledFrom != nil {
+		t.Fatalf("moved task = %+v, want original 2024-01-01 and count 1", got)
+	}
+	for _, task := range []*models.Task{done, future, backlog, daily} {
+		if got := getTask(t, d, task.ID); got.Date != task.Date {
+			t.Errorf("%s date = %s, want unchanged %s", task.Title, got.Date, task.Date)
+		}
+	}
+
+	// 再次转移时累计次数，最初的日期保持不变；同一天重复转移没有效果
+	if n, err := d.RolloverTasks("2024-01-03", false); err != nil || n != 3 {
+		t.Fatalf("second rollover = %d, %v, want 3", n, err)
+	}
+	if got := getTask(t, d, todo.ID); got.Date != "2024-01-03" || got.OriginalDate != "2024-01-01" || got.RolloverCount != 2 {
+		t.Fatalf("task after second rollover = %+v", got)
+	}
+	if n, err := d.RolloverTasks("2024-01-03", false); err != nil || n != 0 {
+		t.Fatalf("repeated rollover = %d, %v, want 0", n, err)
+	}
+}
+
+func TestRolloverCopy(t *testing.T) {
+	d := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+	todo := addTask(t, d, "写周报", "2024-01-01", models.StatusTodo, nil)
+	addTask(t, d, "整理数据", "", models.StatusTodo, todo)
+	addTask(t, d, "画图", "", models.StatusDone, todo)
+	fromCompletion := createSeries(t, d, "2024-01-01", "FREQ=DAILY;FROM=COMPLETION")
+
+	// 复制方式下原来的任务保留，完成后重复的任务不复制
+	if n, err := d.RolloverTasks("2024-01-02", true); err != nil || n != 1 {
+		t.Fatalf("rolled over = %d, %v, want 1", n, err)
+	}
+	if got := getTask(t, d, todo.ID); got.Date != "2024-01-01" || got.RolloverCount != 0 {
+		t.Fatalf("original = %+v, want unchanged", got)
+	}
+	if got := getTask(t, d, fromCompletion.ID); got.Date != "2024-01-01" {
+		t.Fatalf("recurring task date = %s, want unchanged", got.Date)
+	}
+
+	copies, err := d.GetTasksByDate("2024-01-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var first *models.Task
+	for _, task := range copies {
+		if task.ParentID == nil {
+			first = task
+		}
+	}
+	if len(copies) != 2 || first == nil || first.RolledFrom == nil || *first.RolledFrom != todo.ID ||
+		first.OriginalDate != "2024-01-01" || first.RolloverCount != 1 {
+		t.Fatalf("copies = %+v, want the task and its unfinished subtask", copies)
+	}
+
+	// 再次转移时复制副本而不是原来的任务
+	if n, err := d.RolloverTasks("2024-01-03", true); err != nil || n != 1 {
+		t.Fatalf("second rollover = %d, %v, want 1", n, err)
+	}
+	copies, err = d.GetTasksByDate("2024-01-03")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var second *models.Task
+	for _, task := range copies {
+		if task.ParentID == nil {
+			second = task
+		}
+	}
+	if second == nil || *second.RolledFrom != first.ID || second.OriginalDate != "2024-01-01" || second.RolloverCount != 2 {
+		t.Fatalf("second copy = %+v", second)
+	}
+}
+
+func TestGetMostRolledOver(t *testing.T) {
+	d := openTestDB(t, filepath.Join(t.TempDir(), "test.db"))
+	often := addTask(t, d, "写周报", "2024-01-01", models.StatusTodo, nil)
+	once := addTask(t, d, "读书", "2024-01-03", models.StatusTodo, nil)
+	addTask(t, d, "买菜", "2024-01-04", models.StatusTodo, nil)
+
+	for _, date := range []string{"2024-01-02", "2024-01-03", "2024-01-04"} {
+		if _, err := d.RolloverTasks(date, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	end := time.Date(2024, 1, 7, 0, 0, 0, 0, time.Local)
+	tasks, err := d.GetMostRolledOver(start, end, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 2 || tasks[0].ID != often.ID || tasks[0].RolloverCount != 3 || tasks[1].ID != once.ID || tasks[1].RolloverCount != 1 {
+		t.Fatalf("most rolled over = %+v, want 写周报 (3) then 读书 (1)", tasks)
+	}
+
+	if tasks, err := d.GetMostRolledOver(start, end, 1); err != nil || len(tasks) != 1 || tasks[0].ID != often.ID {
+		t.Fatalf("limit 1 = %+v, %v", tasks, err)
+	}
+	// 只包含当前日期在范围内的任务
+	if tasks, err := d.GetMostRolledOver(start, start.AddDate(0, 0, 2), 5); err != nil || len(tasks) != 0 {
+		t.Fatalf("earlier range = %+v, %v, want none", tasks, err)
+	}
+}
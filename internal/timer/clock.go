@@ -0,0 +1,155 @@
+package timer
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock 为计时引擎提供时间来源，便于在测试中替换
+type Clock interface {
+	// Now 返回当前时间
+	Now() time.Time
+	// Monotonic 返回单调时钟读数，系统休眠期间不增加
+	Monotonic() time.Duration
+	// Every 每隔 d 调用一次 fn，直到 ctx 被取消
+	Every(ctx context.Context, d time.Duration, fn func())
+}
+
+// SystemClock 使用系统时间的默认时钟
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+// 单调时钟的起点，time.Since 使用不受系统时间调整影响的单调读数
+var processStart = time.Now()
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Monotonic() time.Duration {
+	return time.Since(processStart)
+}
+
+func (systemClock) Every(ctx context.Context, d time.Duration, fn func()) {
+	ticker := time.NewTicker(d)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				fn()
+			}
+		}
+	}()
+}
+
+// ManualClock 手动推进的时钟，Advance 会同步触发到期的回调
+type ManualClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	mono    time.Duration // 休眠期间不增加的单调读数
+	nextID  int
+	tickers map[int]*manualTicker
+}
+
+type manualTicker struct {
+	id     int
+	ctx    context.Context
+	period time.Duration
+	next   time.Time
+	fn     func()
+}
+
+// NewManualClock 创建一个从 start 开始的手动时钟
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{
+		now:     start,
+		tickers: make(map[int]*manualTicker),
+	}
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *ManualClock) Monotonic() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.mono
+}
+
+func (c *ManualClock) Every(ctx context.Context, d time.Duration, fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.nextID++
+	id := c.nextID
+	c.tickers[id] = &manualTicker{id: id, ctx: ctx, period: d, next: c.now.Add(d), fn: fn}
+}
+
+// Advance 将时钟向前推进 d，并按时间顺序依次触发到期的回调
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	target := c.now.Add(d)
+	c.mu.Unlock()
+
+	for {
+		c.mu.Lock()
+		due := c.dueTickers(target)
+		if len(due) == 0 {
+			c.mono += target.Sub(c.now)
+			c.now = target
+			c.mu.Unlock()
+			return
+		}
+		t := due[0]
+		c.mono += t.next.Sub(c.now)
+		c.now = t.next
+		t.next = t.next.Add(t.period)
+		fn := t.fn
+		c.mu.Unlock()
+
+		// 回调中可能会停止或新建定时器，因此不能持有锁
+		fn()
+	}
+}
+
+// Suspend 模拟系统休眠 d：墙上时间前进而单调时钟不变，期间不触发回调
+func (c *ManualClock) Suspend(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	for _, t := range c.tickers {
+		t.next = t.next.Add(d)
+	}
+}
+
+func (c *ManualClock) dueTickers(target time.Time) []*manualTicker {
+	var due []*manualTicker
+	for id, t := range c.tickers {
+		// 已取消的定时器不再触发
+		if t.ctx.Err() != nil {
+			delete(c.tickers, id)
+			continue
+		}
+		if !t.next.After(target) {
+			due = append(due, t)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].next.Equal(due[j].next) {
+			return due[i].id < due[j].id
+		}
+		return due[i].next.Before(due[j].next)
+	})
+	return due
+}
@@ -0,0 +1,622 @@
+package timer
+
+import (
+	"TodoList/internal/config"
+	"TodoList/internal/models"
+	"context"
+	"sync"
+	"time"
+)
+
+// Phase 表示番茄钟所处的阶段
+type Phase int
+
+const (
+	PhaseWork Phase = iota
+	PhaseShortBreak
+	PhaseLongBreak
+)
+
+func (p Phase) String() string {
+	switch p {
+	case PhaseShortBreak:
+		return "short_break"
+	case PhaseLongBreak:
+		return "long_break"
+	default:
+		return "work"
+	}
+}
+
+// IsBreak 返回是否为休息阶段
+func (p Phase) IsBreak() bool {
+	return p == PhaseShortBreak || p == PhaseLongBreak
+}
+
+// SuspendPolicy 系统休眠期间正在计时的阶段如何处理
+type SuspendPolicy string
+
+const (
+	SuspendCount SuspendPolicy = "count" // 休眠时间照常计入，期间结束的阶段会被补记
+	SuspendPause SuspendPolicy = "pause" // 在休眠开始时暂停计时
+	SuspendEnd   SuspendPolicy = "end"   // 在休眠开始时打断当前阶段并停在下一阶段
+)
+
+// Config 计时引擎的时长配置
+type Config struct {
+	Work              time.Duration
+	ShortBreak        time.Duration
+	LongBreak         time.Duration
+	LongBreakAfter    int           // 每完成多少个番茄钟进行一次长休息
+	AutoStartBreak    bool          // 工作阶段结束后自动开始休息
+	AutoStartPomodoro bool          // 休息结束后自动开始下一个番茄钟
+	SuspendPolicy     SuspendPolicy // 为空时按 SuspendCount 处理
+}
+
+// NewConfig 根据保存的番茄钟配置生成引擎配置，没有单独设置的项使用全局配置
+func NewConfig(tc *models.TimerConfig, defaults config.PomodoroConfig) Config {
+	cfg := Config{
+		Work:              tc.WorkDuration,
+		ShortBreak:        tc.BreakDuration,
+		LongBreak:         tc.LongBreak,
+		LongBreakAfter:    defaults.LongBreakAfter,
+		AutoStartBreak:    defaults.AutoStartBreak,
+		AutoStartPomodoro: defaults.AutoStartPomodoro,
+		SuspendPolicy:     SuspendPolicy(defaults.SuspendPolicy),
+	}
+	if tc.LongBreakAfter != nil {
+		cfg.LongBreakAfter = *tc.LongBreakAfter
+	}
+	if tc.AutoStartBreak != nil {
+		cfg.AutoStartBreak = *tc.AutoStartBreak
+	}
+	if tc.AutoStartPomodoro != nil {
+		cfg.AutoStartPomodoro = *tc.AutoStartPomodoro
+	}
+	return cfg
+}
+
+// Duration 返回指定阶段的时长
+func (c Config) Duration(p Phase) time.Duration {
+	switch p {
+	case PhaseShortBreak:
+		return c.ShortBreak
+	case PhaseLongBreak:
+		return c.LongBreak
+	default:
+		return c.Work
+	}
+}
+
+// AutoStart 返回进入指定阶段时是否自动开始计时
+func (c Config) AutoStart(p Phase) bool {
+	if p.IsBreak() {
+		return c.AutoStartBreak
+	}
+	return c.AutoStartPomodoro
+}
+
+// 恢复状态时最多补记的阶段数，避免长时间未打开程序时生成大量记录
+const maxReplayedPhases = 8
+
+// 两次计时之间墙上时间比单调时钟多出这么多时，认为系统休眠过
+const suspendThreshold = 5 * time.Second
+
+// Engine 与界面无关的番茄钟状态机
+type Engine struct {
+	mu         sync.Mutex
+	cfg        Config
+	clock      Clock
+	phase      Phase
+	state      models.TimerState
+	remaining  time.Duration
+	duration   time.Duration // 当前阶段的总时长
+	completed  int
+	phaseStart time.Time          // 当前阶段第一次开始计时的时间
+	cancelTick context.CancelFunc // 取消当前的计时协程
+	listeners  []Listener
+	pending    []Event    // 等待发送给监听器的事件，按状态变化的顺序排列
+	emitting   sync.Mutex // 保证同一时间只有一个协程在发送事件
+
+	// 运行时根据单调时钟上的截止时间计算剩余时间，避免累积误差
+	deadline time.Duration // 当前阶段在单调时钟上的结束时刻
+	lastWall time.Time     // 上一次计时的墙上时间
+	lastMono time.Duration // 上一次计时的单调时钟读数
+}
+
+// NewEngine 创建计时引擎，clock 为 nil 时使用系统时钟
+func NewEngine(cfg Config, clock Clock) *Engine {
+	if clock == nil {
+		clock = SystemClock
+	}
+	if cfg.LongBreakAfter <= 0 {
+		cfg.LongBreakAfter = 4
+	}
+
+	return &Engine{
+		cfg:       cfg,
+		clock:     clock,
+		phase:     PhaseWork,
+		state:     models.StateIdle,
+		remaining: cfg.Work,
+		duration:  cfg.Work,
+	}
+}
+
+// Subscribe 注册事件监听器，事件按发生顺序依次发送，监听器可能在调用方或计时协程中执行
+func (e *Engine) Subscribe(l Listener) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.listeners = append(e.listeners, l)
+}
+
+// Start 开始或继续计时
+func (e *Engine) Start() {
+	e.mu.Lock()
+	if e.state == models.StateRunning {
+		e.mu.Unlock()
+		return
+	}
+
+	e.state = models.StateRunning
+	if e.phaseStart.IsZero() {
+		e.phaseStart = e.clock.Now()
+	}
+	e.resetDeadline()
+	e.startTicker()
+	events := []Event{e.event(EventStateChanged)}
+	e.queue(events)
+	e.mu.Unlock()
+
+	e.flush()
+}
+
+// Pause 暂停计时
+func (e *Engine) Pause() {
+	e.mu.Lock()
+	if e.state != models.StateRunning {
+		e.mu.Unlock()
+		return
+	}
+
+	e.syncRemaining()
+	e.haltTicker()
+	e.state = models.StatePaused
+	events := []Event{e.event(EventStateChanged)}
+	e.queue(events)
+	e.mu.Unlock()
+
+	e.flush()
+}
+
+// Resume 继续已暂停的计时
+func (e *Engine) Resume() {
+	e.Start()
+}
+
+// Reset 将当前阶段恢复为完整时长并停止计时
+func (e *Engine) Reset() {
+	e.mu.Lock()
+	e.syncRemaining()
+	events := e.interrupt()
+	events = append(events, e.reset())
+	e.queue(events)
+	e.mu.Unlock()
+
+	e.flush()
+}
+
+// Skip 提前结束当前阶段并进入下一阶段，运行中的计时是否继续取决于自动开始设置
+func (e *Engine) Skip() {
+	e.mu.Lock()
+	e.syncRemaining()
+	events := e.interrupt()
+	events = append(events, e.advance(false)...)
+	e.queue(events)
+	e.mu.Unlock()
+
+	e.flush()
+}
+
+// Close 停止计时协程并移除所有监听器，不会发出事件
+func (e *Engine) Close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.haltTicker()
+	e.listeners = nil
+	e.pending = nil
+}
+
+// SetConfig 更新时长配置，并将当前阶段重置为新的时长
+func (e *Engine) SetConfig(cfg Config) {
+	if cfg.LongBreakAfter <= 0 {
+		cfg.LongBreakAfter = 4
+	}
+
+	e.mu.Lock()
+	e.syncRemaining()
+	// 先按旧配置结束当前阶段，再应用新配置
+	events := e.interrupt()
+	e.cfg = cfg
+	events = append(events, e.reset())
+	e.queue(events)
+	e.mu.Unlock()
+
+	e.flush()
+}
+
+// UpdateConfig 更新时长配置而不打断当前阶段，已开始的阶段保持原有时长
+func (e *Engine) UpdateConfig(cfg Config) {
+	if cfg.LongBreakAfter <= 0 {
+		cfg.LongBreakAfter = 4
+	}
+
+	e.mu.Lock()
+	e.cfg = cfg
+	var events []Event
+	if e.state == models.StateIdle && e.phaseStart.IsZero() {
+		e.duration = cfg.Duration(e.phase)
+		e.remaining = e.duration
+		events = append(events, e.event(EventStateChanged))
+	}
+	e.queue(events)
+	e.mu.Unlock()
+
+	e.flush()
+}
+
+// Config 返回当前的时长配置
+func (e *Engine) Config() Config {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.cfg
+}
+
+// Phase 返回当前阶段
+func (e *Engine) Phase() Phase {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.phase
+}
+
+// State 返回当前运行状态
+func (e *Engine) State() models.TimerState {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.state
+}
+
+// Remaining 返回当前阶段的剩余时间
+func (e *Engine) Remaining() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.remaining
+}
+
+// Completed 返回已完成的工作阶段数
+func (e *Engine) Completed() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.completed
+}
+
+// Snapshot 返回当前阶段的计时快照
+func (e *Engine) Snapshot() models.Timer {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return models.Timer{
+		Duration:  e.duration,
+		Remaining: e.remaining,
+		State:     e.state,
+	}
+}
+
+// Runtime 返回可以保存到数据库的运行状态
+func (e *Engine) Runtime() models.TimerRuntime {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.syncRemaining()
+	return models.TimerRuntime{
+		Phase:      int(e.phase),
+		State:      e.state,
+		Remaining:  e.remaining,
+		Duration:   e.duration,
+		Completed:  e.completed,
+		PhaseStart: e.phaseStart,
+		UpdatedAt:  e.clock.Now(),
+	}
+}
+
+// Restore 恢复保存的运行状态
+// 保存时正在运行的计时器会补记程序关闭期间已经结束的阶段，然后继续计时
+func (e *Engine) Restore(rt models.TimerRuntime) {
+	e.mu.Lock()
+	e.haltTicker()
+	e.phase = Phase(rt.Phase)
+	e.state = rt.State
+	e.completed = rt.Completed
+	e.phaseStart = rt.PhaseStart
+	e.duration = rt.Duration
+	if e.duration <= 0 {
+		e.duration = e.cfg.Duration(e.phase)
+	}
+	e.remaining = min(max(rt.Remaining, 0), e.duration)
+
+	var events []Event
+	if e.state == models.StateRunning {
+		if e.phaseStart.IsZero() {
+			e.phaseStart = rt.UpdatedAt
+		}
+		events = e.replay(rt.UpdatedAt, e.clock.Now().Round(0))
+	}
+	if e.state == models.StateRunning {
+		e.resetDeadline()
+		e.startTicker()
+	}
+	events = append(events, e.event(EventStateChanged))
+	e.queue(events)
+	e.mu.Unlock()
+
+	e.flush()
+}
+
+// replay 补记从 since 到 now 之间已经结束的阶段，两者都是墙上时间，调用时必须持有锁
+func (e *Engine) replay(since, now time.Time) []Event {
+	elapsed := now.Sub(since)
+	if elapsed <= 0 {
+		return nil
+	}
+
+	var events []Event
+	for replayed := 1; elapsed >= e.remaining; replayed++ {
+		elapsed -= e.remaining
+		end := now.Add(-elapsed)
+		e.remaining = 0
+
+		completed := e.event(EventPhaseCompleted)
+		completed.At = end
+		completed.Replayed = true
+		events = append(events, completed)
+
+		// 补记的阶段过多时停在下一阶段的开头，等待用户重新开始
+		if replayed >= maxReplayedPhases {
+			e.state = models.StateIdle
+		}
+		for _, ev := range e.advance(true) {
+			if e.state == models.StateRunning {
+				e.phaseStart = end
+				ev.StartedAt = end
+			}
+			ev.At = end
+			ev.Replayed = true
+			events = append(events, ev)
+		}
+		if e.state != models.StateRunning {
+			return events
+		}
+	}
+
+	e.remaining -= elapsed
+	return events
+}
+
+// tick 由时钟每秒调用一次，ctx 已取消时说明计时协程已被替换，忽略这次调用
+func (e *Engine) tick(ctx context.Context) {
+	e.mu.Lock()
+	if ctx.Err() != nil || e.state != models.StateRunning {
+		e.mu.Unlock()
+		return
+	}
+
+	now, mono := e.clock.Now(), e.clock.Monotonic()
+	// 去掉单调时钟读数后比较墙上时间，多出的部分就是系统休眠的时长
+	gap := now.Round(0).Sub(e.lastWall.Round(0)) - (mono - e.lastMono)
+	if gap >= suspendThreshold {
+		events := e.resume(now, mono)
+		e.queue(events)
+		e.mu.Unlock()
+		e.flush()
+		return
+	}
+
+	e.lastWall, e.lastMono = now, mono
+	e.remaining = max(e.deadline-mono, 0).Round(time.Second)
+	events := []Event{e.event(EventTick)}
+
+	if e.remaining == 0 {
+		events = append(events, e.event(EventPhaseCompleted))
+		events = append(events, e.advance(true)...)
+	}
+	e.queue(events)
+	e.mu.Unlock()
+
+	e.flush()
+}
+
+// resume 在检测到系统休眠后按 SuspendPolicy 处理当前阶段，调用时必须持有锁
+func (e *Engine) resume(now time.Time, mono time.Duration) []Event {
+	// 单调时钟在休眠期间不前进，休眠开始时的剩余时间仍以截止时间为准
+	suspendedAt := e.lastWall.Round(0)
+	e.remaining = max(e.deadline-e.lastMono, 0)
+
+	switch e.cfg.SuspendPolicy {
+	case SuspendPause:
+		e.haltTicker()
+		e.state = models.StatePaused
+		return []Event{e.event(EventStateChanged)}
+
+	case SuspendEnd:
+		var events []Event
+		for _, ev := range e.interrupt() {
+			ev.At = suspendedAt
+			events = append(events, ev)
+		}
+		e.haltTicker()
+		e.state = models.StateIdle
+		return append(events, e.advance(false)...)
+	}
+
+	events := e.replay(suspendedAt, now.Round(0))
+	if e.state == models.StateRunning {
+		e.resetDeadline()
+		events = append(events, e.event(EventTick))
+	} else {
+		e.haltTicker()
+	}
+	return events
+}
+
+// advance 切换到下一阶段，调用时必须持有锁
+func (e *Engine) advance(completed bool) []Event {
+	next := PhaseWork
+	if e.phase == PhaseWork {
+		if completed {
+			e.completed++
+		}
+		next = PhaseShortBreak
+		if completed && e.completed%e.cfg.LongBreakAfter == 0 {
+			next = PhaseLongBreak
+		}
+	}
+
+	e.phase = next
+	e.duration = e.cfg.Duration(next)
+	e.remaining = e.duration
+	e.phaseStart = time.Time{}
+
+	// 没有开启自动开始时停在新阶段的开头，等待用户手动开始
+	waiting := e.state == models.StateRunning && !e.cfg.AutoStart(next)
+	if waiting {
+		e.haltTicker()
+		e.state = models.StateIdle
+	}
+
+	if e.state == models.StateRunning {
+		e.phaseStart = e.clock.Now()
+		e.resetDeadline()
+	} else {
+		e.state = models.StateIdle
+	}
+
+	events := []Event{e.event(EventPhaseStarted)}
+	if waiting {
+		events = append(events, e.event(EventPhaseWaiting))
+	}
+	return events
+}
+
+// reset 停止计时并恢复当前阶段的完整时长，调用时必须持有锁
+func (e *Engine) reset() Event {
+	e.haltTicker()
+	e.state = models.StateIdle
+	e.duration = e.cfg.Duration(e.phase)
+	e.remaining = e.duration
+	e.phaseStart = time.Time{}
+	return e.event(EventStateChanged)
+}
+
+// interrupt 当前阶段已开始时生成打断事件，调用时必须持有锁
+func (e *Engine) interrupt() []Event {
+	if !e.started() {
+		return nil
+	}
+	return []Event{e.event(EventPhaseInterrupted)}
+}
+
+// started 返回当前阶段是否已经开始计时，调用时必须持有锁
+func (e *Engine) started() bool {
+	return !e.phaseStart.IsZero() && e.remaining < e.duration
+}
+
+// resetDeadline 根据剩余时间重新计算截止时间，调用时必须持有锁
+func (e *Engine) resetDeadline() {
+	e.lastWall, e.lastMono = e.clock.Now(), e.clock.Monotonic()
+	e.deadline = e.lastMono + e.remaining
+}
+
+// syncRemaining 运行中按截止时间更新剩余时间，调用时必须持有锁
+func (e *Engine) syncRemaining() {
+	if e.state == models.StateRunning {
+		e.remaining = max(e.deadline-e.clock.Monotonic(), 0)
+	}
+}
+
+// startTicker 启动新的计时协程并取消旧的，调用时必须持有锁
+func (e *Engine) startTicker() {
+	e.haltTicker()
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancelTick = cancel
+	e.clock.Every(ctx, time.Second, func() { e.tick(ctx) })
+}
+
+// haltTicker 停止计时协程，调用时必须持有锁
+func (e *Engine) haltTicker() {
+	if e.cancelTick != nil {
+		e.cancelTick()
+		e.cancelTick = nil
+	}
+}
+
+// event 根据当前状态构造事件，调用时必须持有锁
+func (e *Engine) event(t EventType) Event {
+	return Event{
+		Type:      t,
+		Phase:     e.phase,
+		State:     e.state,
+		Remaining: e.remaining,
+		Completed: e.completed,
+		StartedAt: e.phaseStart,
+		Elapsed:   e.duration - e.remaining,
+		At:        e.clock.Now(),
+	}
+}
+
+// queue 将事件加入待发送队列，调用时必须持有锁
+func (e *Engine) queue(events []Event) {
+	e.pending = append(e.pending, events...)
+}
+
+// flush 按顺序发送队列中的事件，调用时不能持有锁
+// 监听器中再次调用引擎方法时，新产生的事件会在当前事件之后发送，而不是嵌套发送
+func (e *Engine) flush() {
+	for e.emitting.TryLock() {
+		e.emit()
+		e.emitting.Unlock()
+
+		// 释放前其他协程可能刚加入了事件，需要再检查一次
+		e.mu.Lock()
+		more := len(e.pending) > 0
+		e.mu.Unlock()
+		if !more {
+			return
+		}
+	}
+}
+
+// Drain 等待已经产生的事件全部发送给监听器后返回，用于退出前确保打断等事件已经处理。
+// 其他协程正在发送事件时会等它发送完，不能在监听器中调用
+func (e *Engine) Drain() {
+	e.emitting.Lock()
+	defer e.emitting.Unlock()
+	e.emit()
+}
+
+// emit 发送队列中的事件直到队列为空，调用方需要持有 emitting
+func (e *Engine) emit() {
+	for {
+		e.mu.Lock()
+		events := e.pending
+		e.pending = nil
+		listeners := append([]Listener(nil), e.listeners...)
+		e.mu.Unlock()
+
+		if len(events) == 0 {
+			return
+		}
+		for _, ev := range events {
+			for _, l := range listeners {
+				l(ev)
+			}
+		}
+	}
+}
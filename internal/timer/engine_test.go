@@ -0,0 +1,370 @@
+package timer
+
+import (
+	"TodoList/internal/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testStart = time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+func testConfig() Config {
+	return Config{
+		Work:              25 * time.Minute,
+		ShortBreak:        5 * time.Minute,
+		LongBreak:         15 * time.Minute,
+		LongBreakAfter:    2,
+		AutoStartBreak:    true,
+		AutoStartPomodoro: true,
+	}
+}
+
+// recorder 记录引擎发出的事件
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func record(e *Engine) *recorder {
+	r := &recorder{}
+	e.Subscribe(func(ev Event) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.events = append(r.events, ev)
+	})
+	return r
+}
+
+// count 返回指定类型的事件数量，replayed 为 true 时只统计补记的事件
+func (r *recorder) count(t EventType, replayed bool) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, ev := range r.events {
+		if ev.Type == t && (!replayed || ev.Replayed) {
+			n++
+		}
+	}
+	return n
+}
+
+// last 返回最后一个指定类型的事件
+func (r *recorder) last(t EventType) (Event, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := len(r.events) - 1; i >= 0; i-- {
+		if r.events[i].Type == t {
+			return r.events[i], true
+		}
+	}
+	return Event{}, false
+}
+
+func expectPhase(t *testing.T, e *Engine, phase Phase, state models.TimerState, completed int) {
+	t.Helper()
+	if got := e.Phase(); got != phase {
+		t.Fatalf("phase = %v, want %v", got, phase)
+	}
+	if got := e.State(); got != state {
+		t.Fatalf("state = %v, want %v", got, state)
+	}
+	if got := e.Completed(); got != completed {
+		t.Fatalf("completed = %d, want %d", got, completed)
+	}
+}
+
+func TestEngineLongBreakCadence(t *testing.T) {
+	clock := NewManualClock(testStart)
+	e := NewEngine(testConfig(), clock)
+	defer e.Close()
+	r := record(e)
+
+	e.Start()
+	clock.Advance(25 * time.Minute)
+	expectPhase(t, e, PhaseShortBreak, models.StateRunning, 1)
+
+	clock.Advance(5 * time.Minute)
+	expectPhase(t, e, PhaseWork, models.StateRunning, 1)
+
+	// 每完成 LongBreakAfter 个番茄钟进行一次长休息
+	clock.Advance(25 * time.Minute)
+	expectPhase(t, e, PhaseLongBreak, models.StateRunning, 2)
+	if got := e.Remaining(); got != 15*time.Minute {
+		t.Fatalf("remaining = %v, want 15m", got)
+	}
+
+	clock.Advance(15 * time.Minute)
+	expectPhase(t, e, PhaseWork, models.StateRunning, 2)
+
+	if got := r.count(EventPhaseCompleted, false); got != 4 {
+		t.Fatalf("completed events = %d, want 4", got)
+	}
+}
+
+func TestEnginePauseKeepsRemaining(t *testing.T) {
+	clock := NewManualClock(testStart)
+	cfg := testConfig()
+	cfg.AutoStartBreak = false
+	e := NewEngine(cfg, clock)
+	defer e.Close()
+	r := record(e)
+
+	e.Start()
+	clock.Advance(10 * time.Minute)
+	e.Pause()
+	if got := e.Remaining(); got != 15*time.Minute {
+		t.Fatalf("remaining after pause = %v, want 15m", got)
+	}
+
+	// 暂停期间不计时
+	clock.Advance(time.Hour)
+	if got := e.Remaining(); got != 15*time.Minute {
+		t.Fatalf("remaining while paused = %v, want 15m", got)
+	}
+	expectPhase(t, e, PhaseWork, models.StatePaused, 0)
+
+	e.Resume()
+	clock.Advance(15*time.Minute - time.Second)
+	expectPhase(t, e, PhaseWork, models.StateRunning, 0)
+
+	// 没有自动开始休息时停在休息的开头
+	clock.Advance(time.Second)
+	expectPhase(t, e, PhaseShortBreak, models.StateIdle, 1)
+	if got := r.count(EventPhaseWaiting, false); got != 1 {
+		t.Fatalf("waiting events = %d, want 1", got)
+	}
+}
+
+func TestEngineSkipAndReset(t *testing.T) {
+	clock := NewManualClock(testStart)
+	e := NewEngine(testConfig(), clock)
+	defer e.Close()
+	r := record(e)
+
+	e.Start()
+	clock.Advance(5 * time.Minute)
+	e.Skip()
+
+	// 跳过的工作阶段记为打断，不计入完成数
+	expectPhase(t, e, PhaseShortBreak, models.StateRunning, 0)
+	ev, ok := r.last(EventPhaseInterrupted)
+	if !ok || ev.Phase != PhaseWork || ev.Elapsed != 5*time.Minute {
+		t.Fatalf("interrupted event = %+v, want work phase with 5m elapsed", ev)
+	}
+	if rec := ev.Record(1); rec == nil || !rec.Interrupted || rec.Duration != 300 {
+		t.Fatalf("record = %+v, want interrupted 300s record", rec)
+	}
+
+	clock.Advance(time.Minute)
+	e.Reset()
+	expectPhase(t, e, PhaseShortBreak, models.StateIdle, 0)
+	if got := e.Remaining(); got != 5*time.Minute {
+		t.Fatalf("remaining after reset = %v, want 5m", got)
+	}
+	if got := r.count(EventPhaseInterrupted, false); got != 2 {
+		t.Fatalf("interrupted events = %d, want 2", got)
+	}
+
+	// 重置后不再计时
+	clock.Advance(10 * time.Minute)
+	if got := e.Remaining(); got != 5*time.Minute {
+		t.Fatalf("remaining after reset and advance = %v, want 5m", got)
+	}
+}
+
+func TestEngineRestoreReplaysLimitedPhases(t *testing.T) {
+	clock := NewManualClock(testStart)
+	e := NewEngine(testConfig(), clock)
+	defer e.Close()
+	r := record(e)
+
+	// 程序关闭了一整天，只补记 maxReplayedPhases 个阶段
+	e.Restore(models.TimerRuntime{
+		Phase:      int(PhaseWork),
+		State:      models.StateRunning,
+		Remaining:  25 * time.Minute,
+		Duration:   25 * time.Minute,
+		PhaseStart: testStart.Add(-24 * time.Hour),
+		UpdatedAt:  testStart.Add(-24 * time.Hour),
+	})
+
+	if got := r.count(EventPhaseCompleted, true); got != maxReplayedPhases {
+		t.Fatalf("replayed phases = %d, want %d", got, maxReplayedPhases)
+	}
+	if got := e.State(); got != models.StateIdle {
+		t.Fatalf("state = %v, want idle", got)
+	}
+	if got := e.Remaining(); got != e.Config().Duration(e.Phase()) {
+		t.Fatalf("remaining = %v, want full duration of %v", got, e.Phase())
+	}
+
+	// 停下后不再计时
+	clock.Advance(time.Hour)
+	if got := r.count(EventPhaseCompleted, false); got != maxReplayedPhases {
+		t.Fatalf("completed events after advance = %d, want %d", got, maxReplayedPhases)
+	}
+}
+
+func TestEngineRestoreReplaysShortAbsence(t *testing.T) {
+	clock := NewManualClock(testStart)
+	e := NewEngine(testConfig(), clock)
+	defer e.Close()
+
+	// 关闭 30 分钟：工作阶段剩余 20 分钟结束，5 分钟休息结束，新的工作阶段计时 5 分钟
+	e.Restore(models.TimerRuntime{
+		Phase:      int(PhaseWork),
+		State:      models.StateRunning,
+		Remaining:  20 * time.Minute,
+		Duration:   25 * time.Minute,
+		PhaseStart: testStart.Add(-35 * time.Minute),
+		UpdatedAt:  testStart.Add(-30 * time.Minute),
+	})
+
+	expectPhase(t, e, PhaseWork, models.StateRunning, 1)
+	if got := e.Remaining(); got != 20*time.Minute {
+		t.Fatalf("remaining = %v, want 20m", got)
+	}
+}
+
+func TestEngineSuspendPolicies(t *testing.T) {
+	tests := []struct {
+		policy    SuspendPolicy
+		phase     Phase
+		state     models.TimerState
+		completed int
+		remaining time.Duration
+	}{
+		// 休眠时间照常计入，工作阶段在休眠期间结束并补记
+		{SuspendCount, PhaseShortBreak, models.StateIdle, 1, 5 * time.Minute},
+		// 休眠开始时暂停，剩余时间不变
+		{SuspendPause, PhaseWork, models.StatePaused, 0, 15 * time.Minute},
+		// 休眠开始时打断当前阶段并停在下一阶段
+		{SuspendEnd, PhaseShortBreak, models.StateIdle, 0, 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			clock := NewManualClock(testStart)
+			cfg := testConfig()
+			cfg.AutoStartBreak = false
+			cfg.SuspendPolicy = tt.policy
+			e := NewEngine(cfg, clock)
+			defer e.Close()
+			r := record(e)
+
+			e.Start()
+			clock.Advance(10 * time.Minute)
+			clock.Suspend(30 * time.Minute)
+			clock.Advance(time.Second)
+
+			expectPhase(t, e, tt.phase, tt.state, tt.completed)
+			if got := e.Remaining(); got != tt.remaining {
+				t.Fatalf("remaining = %v, want %v", got, tt.remaining)
+			}
+
+			switch tt.policy {
+			case SuspendCount:
+				ev, ok := r.last(EventPhaseCompleted)
+				if !ok || !ev.Replayed || !ev.At.Equal(testStart.Add(25*time.Minute)) {
+					t.Fatalf("completed event = %+v, want replayed at the original deadline", ev)
+				}
+			case SuspendEnd:
+				ev, ok := r.last(EventPhaseInterrupted)
+				if !ok || !ev.At.Equal(testStart.Add(10*time.Minute)) {
+					t.Fatalf("interrupted event = %+v, want at suspend time", ev)
+				}
+			}
+
+			// 暂停和停止后不再计时
+			clock.Advance(time.Minute)
+			if got := e.Remaining(); got != tt.remaining {
+				t.Fatalf("remaining after advance = %v, want %v", got, tt.remaining)
+			}
+		})
+	}
+}
+
+func TestEngineConcurrentControl(t *testing.T) {
+	clock := NewManualClock(testStart)
+	e := NewEngine(testConfig(), clock)
+	defer e.Close()
+	r := record(e)
+
+	const rounds = 200
+	var wg sync.WaitGroup
+	run := func(fn func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				fn(i)
+			}
+		}()
+	}
+
+	// 界面按钮、本地接口、设置修改和计时协程同时操作同一个引擎
+	run(func(i int) {
+		e.Start()
+		e.Pause()
+		e.Resume()
+	})
+	run(func(i int) {
+		e.Reset()
+		e.Skip()
+	})
+	run(func(i int) {
+		cfg := testConfig()
+		cfg.Work = time.Duration(20+i%10) * time.Minute
+		if i%2 == 0 {
+			e.SetConfig(cfg)
+		} else {
+			e.UpdateConfig(cfg)
+		}
+	})
+	run(func(i int) {
+		clock.Advance(30 * time.Second)
+	})
+	run(func(i int) {
+		e.Snapshot()
+		e.Runtime()
+		r.count(EventTick, false)
+	})
+	wg.Wait()
+
+	if remaining, duration := e.Remaining(), e.Config().Duration(e.Phase()); remaining < 0 || remaining > duration {
+		t.Fatalf("remaining = %v, want within [0, %v]", remaining, duration)
+	}
+}
+
+func TestEngineDrainWaitsForOtherEmitter(t *testing.T) {
+	clock := NewManualClock(testStart)
+	e := NewEngine(testConfig(), clock)
+	defer e.Close()
+	r := record(e)
+
+	// 第一个事件发送时阻塞，模拟计时协程正在发送事件
+	blocked, release := make(chan struct{}), make(chan struct{})
+	var once sync.Once
+	e.Subscribe(func(ev Event) {
+		once.Do(func() {
+			close(blocked)
+			<-release
+		})
+	})
+	go e.Start()
+	<-blocked
+
+	// 此时 Reset 产生的打断事件只能排队，由正在发送的协程送出
+	clock.Advance(time.Minute)
+	e.Reset()
+	if got := r.count(EventPhaseInterrupted, false); got != 0 {
+		t.Fatalf("interrupted events before drain = %d, want 0", got)
+	}
+
+	time.AfterFunc(10*time.Millisecond, func() { close(release) })
+	e.Drain()
+	if got := r.count(EventPhaseInterrupted, false); got != 1 {
+		t.Fatalf("interrupted events after drain = %d, want 1", got)
+	}
+}
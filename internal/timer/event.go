@@ -0,0 +1,52 @@
+package timer
+
+import (
+	"TodoList/internal/models"
+	"time"
+)
+
+// EventType 表示计时引擎发出的事件类型
+type EventType int
+
+const (
+	EventTick             EventType = iota // 剩余时间更新
+	EventStateChanged                      // 开始、暂停或重置
+	EventPhaseStarted                      // 进入新的阶段
+	EventPhaseCompleted                    // 阶段正常结束
+	EventPhaseInterrupted                  // 阶段被跳过或重置
+	EventPhaseWaiting                      // 没有开启自动开始，新阶段等待手动开始
+)
+
+// Event 描述一次计时引擎的状态变化
+type Event struct {
+	Type      EventType
+	Phase     Phase             // 事件所属的阶段
+	State     models.TimerState // 事件发生后的运行状态
+	Remaining time.Duration     // 当前阶段的剩余时间
+	Completed int               // 已完成的工作阶段数
+	StartedAt time.Time         // 阶段开始的时间
+	Elapsed   time.Duration     // 阶段内已计时的时长
+	At        time.Time         // 事件发生的时间
+	Replayed  bool              // 补记的、程序关闭或系统休眠期间发生的事件
+}
+
+// Listener 接收计时引擎的事件
+type Listener func(Event)
+
+// Record 将工作阶段的结束事件转换为番茄钟记录，其他事件返回 nil
+func (e Event) Record(taskID int64) *models.PomodoroRecord {
+	if e.Phase != PhaseWork || e.StartedAt.IsZero() || e.Elapsed <= 0 {
+		return nil
+	}
+	if e.Type != EventPhaseCompleted && e.Type != EventPhaseInterrupted {
+		return nil
+	}
+
+	return &models.PomodoroRecord{
+		TaskID:      taskID,
+		StartTime:   e.StartedAt,
+		EndTime:     e.At,
+		Duration:    int64(e.Elapsed.Seconds()),
+		Interrupted: e.Type == EventPhaseInterrupted,
+	}
+}
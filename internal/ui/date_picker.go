@@ -0,0 +1,167 @@
+package ui
+
+import (
+	"TodoList/internal/models"
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// DatePicker 日期选择器，包含前一天、后一天按钮和弹出的月历，
+// 月历中有任务或番茄钟的日期下方显示圆点
+type DatePicker struct {
+	container *fyne.Container
+	button    *widget.Button // 显示当前日期，点击弹出月历
+	date      time.Time
+	onChanged func(time.Time)
+
+	// 查询日期范围内每天的任务和番茄钟数量
+	activity func(start, end time.Time) (map[string]*models.DateActivity, error)
+
+	popup      *widget.PopUp
+	month      time.Time // 月历显示的月份
+	monthLabel *widget.Label
+	days       *fyne.Container
+}
+
+func NewDatePicker(activity func(start, end time.Time) (map[string]*models.DateActivity, error), onChanged func(time.Time)) *DatePicker {
+	p := &DatePicker{
+		date:       todayDate(),
+		activity:   activity,
+		onChanged:  onChanged,
+		monthLabel: widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		days:       container.NewGridWithColumns(7),
+	}
+	p.button = widget.NewButton(p.date.Format("2006-01-02"), p.showCalendar)
+
+	p.container = container.NewHBox(
+		widget.NewButtonWithIcon("", theme.NavigateBackIcon(), p.PrevDay),
+		p.button,
+		widget.NewButtonWithIcon("", theme.NavigateNextIcon(), p.NextDay),
+		widget.NewButton("今天", p.Today),
+	)
+	return p
+}
+
+// Date 返回当前选择的日期
+func (p *DatePicker) Date() time.Time {
+	return p.date
+}
+
+// SetDate 选择日期，日期变化时调用 onChanged
+func (p *DatePicker) SetDate(date time.Time) {
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
+	if date.Equal(p.date) {
+		return
+	}
+	p.date = date
+	p.button.SetText(date.Format("2006-01-02"))
+	if p.onChanged != nil {
+		p.onChanged(date)
+	}
+}
+
+// PrevDay 选择前一天
+func (p *DatePicker) PrevDay() {
+	p.SetDate(p.date.AddDate(0, 0, -1))
+}
+
+// NextDay 选择后一天
+func (p *DatePicker) NextDay() {
+	p.SetDate(p.date.AddDate(0, 0, 1))
+}
+
+// Today 选择今天
+func (p *DatePicker) Today() {
+	p.SetDate(todayDate())
+}
+
+// showCalendar 在按钮下方弹出当前日期所在月份的月历
+func (p *DatePicker) showCalendar() {
+	c := fyne.CurrentApp().Driver().CanvasForObject(p.button)
+	if c == nil {
+		return
+	}
+	if p.popup == nil {
+		header := container.NewBorder(nil, nil,
+			widget.NewButtonWithIcon("", theme.NavigateBackIcon(), func() { p.showMonth(p.month.AddDate(0, -1, 0)) }),
+			widget.NewButtonWithIcon("", theme.NavigateNextIcon(), func() { p.showMonth(p.month.AddDate(0, 1, 0)) }),
+			p.monthLabel,
+		)
+		p.popup = widget.NewPopUp(container.NewVBox(header, p.days), c)
+	}
+
+	p.showMonth(p.date)
+	pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(p.button)
+	p.popup.ShowAtPosition(pos.Add(fyne.NewPos(0, p.button.Size().Height)))
+}
+
+// showMonth 在月历中显示 date 所在的月份，星期按周一到周日排列
+func (p *DatePicker) showMonth(date time.Time) {
+	p.month = time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.Local)
+	last := p.month.AddDate(0, 1, -1)
+	p.monthLabel.SetText(p.month.Format("2006-01"))
+
+	activity, err := p.activity(p.month, last)
+	if err != nil {
+		fmt.Println("Error loading active dates:", err)
+	}
+
+	p.days.RemoveAll()
+	for i := 1; i <= 7; i++ {
+		p.days.Add(widget.NewLabelWithStyle(models.WeekdayName(time.Weekday(i%7)), fyne.TextAlignCenter, fyne.TextStyle{}))
+	}
+	for i := 0; i < (int(p.month.Weekday())+6)%7; i++ {
+		p.days.Add(layout.NewSpacer())
+	}
+	for day := p.month; !day.After(last); day = day.AddDate(0, 0, 1) {
+		p.days.Add(p.newDayCell(day, activity[day.Format("2006-01-02")]))
+	}
+	p.days.Refresh()
+}
+
+// newDayCell 创建月历中的一天，选择的日期高亮显示，今天比其他日期醒目
+func (p *DatePicker) newDayCell(day time.Time, activity *models.DateActivity) fyne.CanvasObject {
+	btn := widget.NewButton(fmt.Sprint(day.Day()), func() {
+		p.popup.Hide()
+		p.SetDate(day)
+	})
+	switch {
+	case day.Equal(p.date):
+		btn.Importance = widget.HighImportance
+	case day.Equal(todayDate()):
+		btn.Importance = widget.MediumImportance
+	default:
+		btn.Importance = widget.LowImportance
+	}
+
+	// 有任务时显示主题色圆点，有番茄钟时显示红色圆点
+	dots := container.NewHBox()
+	if activity != nil && activity.Tasks > 0 {
+		dots.Add(newDot(theme.ColorNamePrimary))
+	}
+	if activity != nil && activity.Timers+activity.Sessions > 0 {
+		dots.Add(newDot(colorNamePriorityHigh))
+	}
+	return container.NewStack(btn, container.NewVBox(layout.NewSpacer(), container.NewCenter(dots)))
+}
+
+// newDot 创建月历中标记日期的小圆点
+func newDot(fill fyne.ThemeColorName) fyne.CanvasObject {
+	dot := canvas.NewRectangle(theme.Color(fill))
+	dot.CornerRadius = 3
+	dot.SetMinSize(fyne.NewSize(6, 6))
+	return dot
+}
+
+// todayDate 返回本地时间今天的零点
+func todayDate() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+}
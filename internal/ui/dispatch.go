@@ -0,0 +1,47 @@
+package ui
+
+import "sync"
+
+// uiQueue 在单独的协程中按顺序执行来自计时协程、配置监听和本地接口的界面更新，
+// 避免多个后台协程同时修改同一组件。队列不限长度，在回调中再次加入任务也不会阻塞。
+type uiQueue struct {
+	mu      sync.Mutex
+	pending []func()
+	wake    chan struct{}
+}
+
+var (
+	uiCalls     = &uiQueue{wake: make(chan struct{}, 1)}
+	uiCallsOnce sync.Once
+)
+
+// runOnUI 将界面更新加入队列，按加入的顺序执行
+func runOnUI(fn func()) {
+	uiCallsOnce.Do(func() { go uiCalls.run() })
+
+	uiCalls.mu.Lock()
+	uiCalls.pending = append(uiCalls.pending, fn)
+	uiCalls.mu.Unlock()
+
+	select {
+	case uiCalls.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *uiQueue) run() {
+	for range q.wake {
+		for {
+			q.mu.Lock()
+			if len(q.pending) == 0 {
+				q.mu.Unlock()
+				break
+			}
+			fn := q.pending[0]
+			q.pending = q.pending[1:]
+			q.mu.Unlock()
+
+			fn()
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package ui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+)
+
+// draggable 可以拖动的内容，拖动时在鼠标旁显示预览文字，松开后以鼠标的绝对位置调用 onDrop
+type draggable struct {
+	widget.BaseWidget
+	content fyne.CanvasObject
+	preview string
+	onDrop  func(pos fyne.Position)
+
+	popup *widget.PopUp
+	pos   fyne.Position // 最近一次拖动时鼠标的绝对位置
+}
+
+func newDraggable(content fyne.CanvasObject, preview string, onDrop func(pos fyne.Position)) *draggable {
+	d := &draggable{content: content, preview: preview, onDrop: onDrop}
+	d.ExtendBaseWidget(d)
+	return d
+}
+
+func (d *draggable) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(d.content)
+}
+
+// Dragged 实现 fyne.Draggable，预览跟随鼠标移动
+func (d *draggable) Dragged(e *fyne.DragEvent) {
+	d.pos = e.AbsolutePosition
+	if d.popup == nil {
+		c := fyne.CurrentApp().Driver().CanvasForObject(d)
+		if c == nil {
+			return
+		}
+		d.popup = widget.NewPopUp(widget.NewLabel(d.preview), c)
+	}
+	// 预览稍微偏离鼠标，避免挡住松开时的位置
+	d.popup.ShowAtPosition(d.pos.Add(fyne.NewPos(12, 12)))
+}
+
+// DragEnd 实现 fyne.Draggable
+func (d *draggable) DragEnd() {
+	if d.popup == nil {
+		return
+	}
+	d.popup.Hide()
+	d.popup = nil
+	if d.onDrop != nil {
+		d.onDrop(d.pos)
+	}
+}
+
+// containsPoint 判断绝对位置 pos 是否在 obj 显示的范围内
+func containsPoint(obj fyne.CanvasObject, pos fyne.Position) bool {
+	if obj == nil || !obj.Visible() {
+		return false
+	}
+	topLeft := fyne.CurrentApp().Driver().AbsolutePositionForObject(obj)
+	size := obj.Size()
+	return pos.X >= topLeft.X && pos.X < topLeft.X+size.Width &&
+		pos.Y >= topLeft.Y && pos.Y < topLeft.Y+size.Height
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"TodoList/internal/config"
+	"TodoList/internal/models"
+	"TodoList/internal/timer"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+)
+
+// Notifier 发送桌面通知，测试时可以换成记录通知内容的实现
+type Notifier interface {
+	Notify(title, content string)
+}
+
+// appNotifier 通过 fyne.App 发送系统通知
+type appNotifier struct {
+	app fyne.App
+}
+
+func (n *appNotifier) Notify(title, content string) {
+	n.app.SendNotification(fyne.NewNotification(title, content))
+}
+
+// 界面中显示的阶段名称
+var phaseNames = map[timer.Phase]string{
+	timer.PhaseWork:       "工作时间",
+	timer.PhaseShortBreak: "休息时间",
+	timer.PhaseLongBreak:  "长休息时间",
+}
+
+// notifyEnabled 返回结束的阶段是否需要发送通知
+func notifyEnabled(cfg config.NotifyConfig, ended timer.Phase) bool {
+	switch ended {
+	case timer.PhaseShortBreak:
+		return cfg.BreakComplete
+	case timer.PhaseLongBreak:
+		return cfg.LongBreakComplete
+	default:
+		return cfg.WorkComplete
+	}
+}
+
+// phaseNotification 生成阶段结束通知的标题和内容，task 可以为 nil
+func phaseNotification(name string, ended, next timer.Phase, waiting bool, task *models.Task) (string, string) {
+	title := fmt.Sprintf("%s: %s结束", name, phaseNames[ended])
+
+	content := "下一阶段: " + phaseNames[next]
+	if waiting {
+		content += "，等待开始"
+	}
+	if task != nil {
+		content += "\n任务: " + task.Title
+	}
+	return title, content
+}
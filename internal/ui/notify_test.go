@@ -0,0 +1,102 @@
+package ui
+
+import (
+	"TodoList/internal/config"
+	"TodoList/internal/models"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNotifier 记录发送的通知
+type fakeNotifier struct {
+	mu            sync.Mutex
+	notifications [][2]string
+}
+
+func (n *fakeNotifier) Notify(title, content string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.notifications = append(n.notifications, [2]string{title, content})
+}
+
+func (n *fakeNotifier) sent() [][2]string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([][2]string(nil), n.notifications...)
+}
+
+func TestNotifyOnPhaseTransition(t *testing.T) {
+	notifier := &fakeNotifier{}
+	tm, clock := newTestManager(t, notifier)
+	a := addTestTimer(t, tm, "专注", 25*time.Minute, 5*time.Minute)
+
+	task := &models.Task{Title: "写周报", Status: models.StatusTodo, CreatedAt: time.Now(), Priority: 1, Date: time.Now().Format("2006-01-02")}
+	if err := tm.db.SaveTask(task); err != nil {
+		t.Fatal(err)
+	}
+	a.SetTask(task)
+
+	// 默认不自动开始休息，工作结束后等待开始
+	a.Start()
+	clock.Advance(25 * time.Minute)
+	flushUI()
+
+	sent := notifier.sent()
+	if len(sent) != 1 {
+		t.Fatalf("notifications = %v, want 1", sent)
+	}
+	title, content := sent[0][0], sent[0][1]
+	if title != "专注: 工作时间结束" {
+		t.Fatalf("title = %q", title)
+	}
+	for _, want := range []string{"下一阶段: 休息时间", "等待开始", "任务: 写周报"} {
+		if !strings.Contains(content, want) {
+			t.Fatalf("content = %q, want it to contain %q", content, want)
+		}
+	}
+
+	// 暂停、重置和跳过不是正常结束，不发送通知
+	a.Start()
+	clock.Advance(time.Minute)
+	a.Stop()
+	a.Reset()
+	a.Toggle()
+	flushUI()
+	if got := len(notifier.sent()); got != 1 {
+		t.Fatalf("notifications after skip = %d, want 1", got)
+	}
+}
+
+func TestNotifyConfigToggles(t *testing.T) {
+	notifier := &fakeNotifier{}
+	tm, clock := newTestManager(t, notifier)
+
+	cfg := config.DefaultConfig().Pomodoro
+	cfg.AutoStartBreak = true
+	cfg.AutoStartPomodoro = true
+	cfg.LongBreakAfter = 2
+	cfg.Notifications = config.NotifyConfig{WorkComplete: false, BreakComplete: true, LongBreakComplete: false}
+	tm.ApplyPomodoroConfig(cfg)
+
+	a := addTestTimer(t, tm, "A", 25*time.Minute, 5*time.Minute)
+	a.Start()
+
+	// 工作 → 短休息 → 工作 → 长休息 → 工作，只有短休息结束时发送通知
+	for _, d := range []time.Duration{25 * time.Minute, 5 * time.Minute, 25 * time.Minute, 15 * time.Minute} {
+		clock.Advance(d)
+		flushUI()
+	}
+
+	sent := notifier.sent()
+	if len(sent) != 1 {
+		t.Fatalf("notifications = %v, want only the short break one", sent)
+	}
+	if sent[0][0] != "A: 休息时间结束" || !strings.Contains(sent[0][1], "下一阶段: 工作时间") {
+		t.Fatalf("notification = %v", sent[0])
+	}
+	if strings.Contains(sent[0][1], "等待开始") || strings.Contains(sent[0][1], "任务:") {
+		t.Fatalf("content = %q, want no waiting or task line", sent[0][1])
+	}
+}
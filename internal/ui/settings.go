@@ -0,0 +1,131 @@
+package ui
+
+import (
+	"TodoList/internal/config"
+	"fmt"
+	"image/color"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 主题模式在界面上的名称
+var themeModeNames = map[string]string{
+	config.ThemeModeLight:  "浅色",
+	config.ThemeModeDark:   "深色",
+	config.ThemeModeSystem: "跟随系统",
+}
+
+// SettingsView 外观设置页
+type SettingsView struct {
+	container     *fyne.Container
+	configManager *config.Manager
+	window        fyne.Window
+
+	modeSelect  *widget.RadioGroup
+	fontSlider  *widget.Slider
+	fontLabel   *widget.Label
+	accentColor string
+	accentRect  *canvas.Rectangle
+
+	mu sync.Mutex // 保护 accentColor，配置重新加载时会在 runOnUI 中修改
+}
+
+func NewSettingsView(configManager *config.Manager, window fyne.Window) *SettingsView {
+	sv := &SettingsView{
+		configManager: configManager,
+		window:        window,
+	}
+	sv.setup()
+	sv.load(configManager.GetConfig().Theme)
+	return sv
+}
+
+func (sv *SettingsView) setup() {
+	title := widget.NewLabelWithStyle("外观设置", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
+
+	sv.modeSelect = widget.NewRadioGroup([]string{
+		themeModeNames[config.ThemeModeLight],
+		themeModeNames[config.ThemeModeDark],
+		themeModeNames[config.ThemeModeSystem],
+	}, nil)
+	sv.modeSelect.Horizontal = true
+
+	sv.fontLabel = widget.NewLabel("")
+	sv.fontSlider = widget.NewSlider(8, 48)
+	sv.fontSlider.OnChanged = func(v float64) {
+		sv.fontLabel.SetText(fmt.Sprintf("%d", int(v)))
+	}
+
+	// 强调色预览和选择
+	sv.accentRect = canvas.NewRectangle(color.Transparent)
+	sv.accentRect.SetMinSize(fyne.NewSize(32, 24))
+	sv.accentRect.CornerRadius = 4
+	pickBtn := widget.NewButton("选择颜色", func() {
+		picker := dialog.NewColorPicker("强调色", "选择界面的强调色", func(c color.Color) {
+			sv.setAccent(formatHexColor(c))
+		}, sv.window)
+		picker.Advanced = true
+		picker.Show()
+	})
+	resetBtn := widget.NewButton("默认", func() {
+		sv.setAccent("")
+	})
+
+	form := widget.NewForm(
+		widget.NewFormItem("主题模式", sv.modeSelect),
+		widget.NewFormItem("字体大小", container.NewBorder(nil, nil, nil, sv.fontLabel, sv.fontSlider)),
+		widget.NewFormItem("强调色", container.NewHBox(sv.accentRect, pickBtn, resetBtn)),
+	)
+
+	saveBtn := widget.NewButton("保存", sv.save)
+	saveBtn.Importance = widget.HighImportance
+
+	sv.container = container.NewVBox(
+		title,
+		form,
+		container.NewHBox(saveBtn),
+	)
+}
+
+// load 将配置显示到表单中
+func (sv *SettingsView) load(cfg config.ThemeConfig) {
+	sv.modeSelect.SetSelected(themeModeNames[cfg.EffectiveMode()])
+	sv.fontSlider.SetValue(float64(cfg.FontSize))
+	sv.setAccent(cfg.AccentColor)
+}
+
+func (sv *SettingsView) setAccent(hex string) {
+	sv.mu.Lock()
+	sv.accentColor = hex
+	sv.mu.Unlock()
+	if c, err := parseHexColor(hex); err == nil {
+		sv.accentRect.FillColor = c
+	} else {
+		sv.accentRect.FillColor = color.Transparent
+	}
+	sv.accentRect.Refresh()
+}
+
+// save 保存设置，主题由配置变化回调统一应用
+func (sv *SettingsView) save() {
+	cfg := sv.configManager.GetConfig().Theme
+	for mode, name := range themeModeNames {
+		if name == sv.modeSelect.Selected {
+			cfg.Mode = mode
+		}
+	}
+	cfg.DarkMode = cfg.Mode == config.ThemeModeDark
+	cfg.FontSize = int(sv.fontSlider.Value)
+	sv.mu.Lock()
+	cfg.AccentColor = sv.accentColor
+	sv.mu.Unlock()
+
+	if err := sv.configManager.UpdateThemeConfig(cfg); err != nil {
+		dialog.ShowError(fmt.Errorf("保存设置失败: %v", err), sv.window)
+	}
+}
@@ -0,0 +1,20 @@
+package ui
+
+import (
+	"TodoList/internal/audio"
+	"TodoList/internal/config"
+)
+
+// applySounds 展开提示音文件的路径并应用到播放器
+func applySounds(player *audio.Player, configManager *config.Manager, cfg config.PomodoroConfig) {
+	sounds := cfg.Sounds
+	for _, sound := range []*config.SoundConfig{&sounds.WorkComplete, &sounds.BreakComplete, &sounds.LongBreakComplete} {
+		if sound.File == "" {
+			continue
+		}
+		if path, err := configManager.ResolvePath(sound.File); err == nil {
+			sound.File = path
+		}
+	}
+	player.Apply(cfg.NotificationSound, sounds)
+}
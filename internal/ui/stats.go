@@ -3,12 +3,13 @@ package ui
 import (
 	"TodoList/internal/storage"
 	"fmt"
+	"strings"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
-	"fyne.io/fyne/v2/widget"
 	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
 )
 
 type StatsView struct {
@@ -17,6 +18,8 @@ type StatsView struct {
 	dateRange     *widget.Select
 	taskStats     *widget.Label
 	pomodoroStats *widget.Label
+	tagStats      *widget.Label
+	rolloverStats *widget.Label
 	refreshBtn    *widget.Button
 }
 
@@ -25,6 +28,8 @@ func NewStatsView(db *storage.Database) *StatsView {
 		db:            db,
 		taskStats:     widget.NewLabel(""),
 		pomodoroStats: widget.NewLabel(""),
+		tagStats:      widget.NewLabel(""),
+		rolloverStats: widget.NewLabel(""),
 	}
 	sv.setup()
 	return sv
@@ -66,6 +71,14 @@ func (sv *StatsView) setup() {
 			widget.NewLabelWithStyle("Pomodoro Statistics", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
 			sv.pomodoroStats,
 		),
+		container.NewVBox(
+			widget.NewLabelWithStyle("Tag Statistics", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			sv.tagStats,
+		),
+		container.NewVBox(
+			widget.NewLabelWithStyle("Most Postponed", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+			sv.rolloverStats,
+		),
 	)
 
 	// 组织整体布局
@@ -111,10 +124,18 @@ func (sv *StatsView) updateStats(timeRange string) {
 		return
 	}
 
-	// 计算完成率
-	var completionRate float64
-	if taskStats.TotalTasks > 0 {
-		completionRate = float64(taskStats.CompletedTasks) / float64(taskStats.TotalTasks) * 100
+	// 获取标签统计
+	tagStats, err := sv.db.GetTagStats(startDate, endDate)
+	if err != nil {
+		fmt.Println("Error getting tag stats:", err)
+		return
+	}
+
+	// 获取推迟次数最多的任务
+	rolledOver, err := sv.db.GetMostRolledOver(startDate, endDate, 10)
+	if err != nil {
+		fmt.Println("Error getting rollover stats:", err)
+		return
 	}
 
 	// 更新任务统计显示
@@ -125,14 +146,20 @@ func (sv *StatsView) updateStats(timeRange string) {
 			"Todo: %d\n"+
 			"Doing: %d\n"+
 			"Done: %d\n"+
-			"Cancelled: %d",
+			"Cancelled: %d\n"+
+			"Subtasks: %d/%d\n"+
+			"Rolled Over: %d (%d times)",
 		taskStats.TotalTasks,
 		taskStats.CompletedTasks,
-		completionRate,
+		taskStats.CompletionRate,
 		taskStats.TodoTasks,
 		taskStats.DoingTasks,
 		taskStats.DoneTasks,
 		taskStats.CancelledTasks,
+		taskStats.DoneSubtasks,
+		taskStats.TotalSubtasks,
+		taskStats.RolledOverTasks,
+		taskStats.TotalRollovers,
 	))
 
 	// 更新番茄钟统计显示
@@ -148,6 +175,26 @@ func (sv *StatsView) updateStats(timeRange string) {
 		pomodoroStats.TodaySessions,
 		float64(pomodoroStats.TodayDuration)/3600,
 	))
+
+	// 更新标签统计显示，每个标签显示完成数量和任务数量
+	lines := make([]string, len(tagStats))
+	for i, stat := range tagStats {
+		lines[i] = fmt.Sprintf("#%s: %d/%d", stat.Tag, stat.Done, stat.Total)
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "No tags")
+	}
+	sv.tagStats.SetText(strings.Join(lines, "\n"))
+
+	// 更新推迟统计显示，每个任务显示推迟次数和最初的日期
+	lines = make([]string, len(rolledOver))
+	for i, task := range rolledOver {
+		lines[i] = fmt.Sprintf("%s: %d (since %s)", task.Title, task.RolloverCount, task.OriginalDate)
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "No postponed tasks")
+	}
+	sv.rolloverStats.SetText(strings.Join(lines, "\n"))
 }
 
 func (sv *StatsView) Container() *fyne.Container {
@@ -0,0 +1,316 @@
+package ui
+
+import (
+	"TodoList/internal/models"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+)
+
+// 编辑截止时间使用的格式
+const dueLayout = "2006-01-02 15:04"
+
+// showTaskEditor 打开任务编辑窗口，保存成功后调用 onSaved
+func showTaskEditor(task *models.Task, parent *TodoList, onSaved func()) {
+	w := fyne.CurrentApp().NewWindow("编辑任务")
+
+	title := widget.NewEntry()
+	title.SetText(task.Title)
+
+	// 描述支持 Markdown，在预览页中查看渲染效果
+	description := widget.NewMultiLineEntry()
+	description.SetText(task.Description)
+	description.SetMinRowsVisible(8)
+	description.Wrapping = fyne.TextWrapWord
+	preview := widget.NewRichTextFromMarkdown(task.Description)
+	preview.Wrapping = fyne.TextWrapWord
+	descriptionTabs := container.NewAppTabs(
+		container.NewTabItem("编辑", description),
+		container.NewTabItem("预览", container.NewVScroll(preview)),
+	)
+	descriptionTabs.OnSelected = func(*container.TabItem) {
+		preview.ParseMarkdown(description.Text)
+	}
+
+	priorityNames := make([]string, len(models.Priorities))
+	for i, p := range models.Priorities {
+		priorityNames[i] = models.PriorityName(p)
+	}
+	priority := widget.NewSelect(priorityNames, nil)
+	priority.SetSelected(models.PriorityName(task.Priority))
+
+	due := widget.NewEntry()
+	due.SetPlaceHolder("2006-01-02 15:04，留空表示没有截止时间")
+	if task.DueAt != nil {
+		due.SetText(task.DueAt.Local().Format(dueLayout))
+	}
+
+	tags := widget.NewEntry()
+	tags.SetPlaceHolder("用空格或逗号分隔，如 #工作 #学习")
+	if len(task.Tags) > 0 {
+		tags.SetText("#" + strings.Join(task.Tags, " #"))
+	}
+
+	// 重复任务显示当前系列的规则
+	var current *models.RecurrenceRule
+	if task.RecurrenceID != nil {
+		if r, err := parent.db.GetRecurrence(*task.RecurrenceID); err == nil {
+			current = &r.Rule
+		} else {
+			fmt.Println("Error loading recurrence:", err)
+		}
+	}
+	repeat := newRecurrenceEditor(current)
+
+	save := func() {
+		text := strings.TrimSpace(title.Text)
+		if text == "" {
+			dialog.ShowError(fmt.Errorf("任务标题不能为空"), w)
+			return
+		}
+
+		var dueAt *time.Time
+		if s := strings.TrimSpace(due.Text); s != "" {
+			t, err := time.ParseInLocation(dueLayout, s, time.Local)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("无效的截止时间 %q，格式应为 %s", s, dueLayout), w)
+				return
+			}
+			dueAt = &t
+		}
+
+		rule, err := repeat.rule()
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+
+		previous := *task
+		task.Title = text
+		task.Description = description.Text
+		task.Priority = models.Priorities[priority.SelectedIndex()]
+		task.DueAt = dueAt
+		task.Tags = models.NormalizeTags(strings.FieldsFunc(tags.Text, func(r rune) bool {
+			return unicode.IsSpace(r) || r == ',' || r == '，'
+		}))
+
+		// future 为 true 时同时修改以后所有的重复，任务和系列在同一个事务中保存
+		apply := func(future bool) {
+			if err := parent.db.SaveRecurringTask(task, rule, future); err != nil {
+				*task = previous
+				dialog.ShowError(err, w)
+				return
+			}
+
+			w.Close()
+			onSaved()
+		}
+
+		// 修改了重复规则时总是作用于以后所有的重复，否则询问修改范围
+		if task.RecurrenceID == nil || ruleString(rule) != ruleString(current) {
+			apply(true)
+			return
+		}
+		showScopeDialog(w, apply, func() { *task = previous })
+	}
+
+	form := widget.NewForm(
+		widget.NewFormItem("标题", title),
+		widget.NewFormItem("优先级", priority),
+		widget.NewFormItem("截止时间", due),
+		widget.NewFormItem("标签", tags),
+		widget.NewFormItem("重复", repeat.content),
+	)
+
+	w.SetContent(container.NewBorder(
+		form,
+		container.NewHBox(
+			widget.NewButton("取消", w.Close),
+			widget.NewButton("保存", save),
+		),
+		nil, nil,
+		descriptionTabs,
+	))
+	w.Resize(fyne.NewSize(520, 480))
+	w.CenterOnScreen()
+	w.Show()
+}
+
+// 重复方式的选项
+const (
+	repeatNone       = "不重复"
+	repeatDaily      = "每天"
+	repeatWeekdays   = "工作日"
+	repeatWeekly     = "每周"
+	repeatMonthly    = "每月"
+	repeatCompletion = "完成后"
+)
+
+// recurrenceEditor 编辑重复规则的控件，根据重复方式显示对应的参数
+type recurrenceEditor struct {
+	kind     *widget.Select
+	interval *widget.Entry      // 每天和完成后重复时间隔的天数
+	weekdays *widget.CheckGroup // 每周重复的星期
+	monthDay *widget.Entry      // 每月重复的日期
+	params   *fyne.Container
+	content  fyne.CanvasObject
+
+	original *models.RecurrenceRule // 修改前的规则，保留界面中不能编辑的间隔
+}
+
+func newRecurrenceEditor(rule *models.RecurrenceRule) *recurrenceEditor {
+	e := &recurrenceEditor{
+		interval: widget.NewEntry(),
+		monthDay: widget.NewEntry(),
+		params:   container.NewHBox(),
+		original: rule,
+	}
+
+	// 星期按周一到周日排列
+	var dayNames []string
+	for i := 1; i <= 7; i++ {
+		dayNames = append(dayNames, models.WeekdayName(time.Weekday(i%7)))
+	}
+	e.weekdays = widget.NewCheckGroup(dayNames, nil)
+	e.weekdays.Horizontal = true
+
+	e.interval.SetText("1")
+	e.monthDay.SetText("1")
+	e.kind = widget.NewSelect([]string{
+		repeatNone, repeatDaily, repeatWeekdays, repeatWeekly, repeatMonthly, repeatCompletion,
+	}, func(string) { e.updateParams() })
+
+	kind := repeatNone
+	switch {
+	case rule == nil:
+	case rule.FromCompletion:
+		kind = repeatCompletion
+		e.interval.SetText(fmt.Sprint(rule.Interval))
+	case rule.Freq == models.FreqDaily:
+		kind = repeatDaily
+		e.interval.SetText(fmt.Sprint(rule.Interval))
+	case rule.IsWeekdays():
+		kind = repeatWeekdays
+	case rule.Freq == models.FreqWeekly:
+		kind = repeatWeekly
+		for _, day := range rule.ByDay {
+			e.weekdays.Selected = append(e.weekdays.Selected, models.WeekdayName(day))
+		}
+	case rule.Freq == models.FreqMonthly:
+		kind = repeatMonthly
+		e.monthDay.SetText(fmt.Sprint(rule.MonthDay))
+	}
+	e.kind.SetSelected(kind)
+
+	e.content = container.NewVBox(e.kind, e.params)
+	return e
+}
+
+// updateParams 显示当前重复方式需要的参数
+func (e *recurrenceEditor) updateParams() {
+	e.params.RemoveAll()
+	switch e.kind.Selected {
+	case repeatDaily:
+		e.params.Add(widget.NewLabel("每"))
+		e.params.Add(e.interval)
+		e.params.Add(widget.NewLabel("天"))
+	case repeatWeekly:
+		e.params.Add(e.weekdays)
+	case repeatMonthly:
+		e.params.Add(e.monthDay)
+		e.params.Add(widget.NewLabel("日"))
+	case repeatCompletion:
+		e.params.Add(widget.NewLabel("完成"))
+		e.params.Add(e.interval)
+		e.params.Add(widget.NewLabel("天后"))
+	}
+	e.params.Refresh()
+}
+
+// rule 返回编辑后的规则，不重复时返回 nil
+func (e *recurrenceEditor) rule() (*models.RecurrenceRule, error) {
+	// 每周和每月重复沿用原来的间隔
+	interval := 1
+	if e.original != nil && !e.original.FromCompletion && e.original.Freq != models.FreqDaily {
+		interval = e.original.Interval
+	}
+
+	var rule *models.RecurrenceRule
+	switch e.kind.Selected {
+	case repeatNone, "":
+		return nil, nil
+	case repeatDaily, repeatCompletion:
+		n, err := strconv.Atoi(strings.TrimSpace(e.interval.Text))
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("间隔天数必须是正整数")
+		}
+		rule = &models.RecurrenceRule{Freq: models.FreqDaily, Interval: n, FromCompletion: e.kind.Selected == repeatCompletion}
+	case repeatWeekdays:
+		rule = &models.RecurrenceRule{Freq: models.FreqWeekly, Interval: 1, ByDay: models.Weekdays}
+	case repeatWeekly:
+		rule = &models.RecurrenceRule{Freq: models.FreqWeekly, Interval: interval}
+		for i := 1; i <= 7; i++ {
+			day := time.Weekday(i % 7)
+			for _, selected := range e.weekdays.Selected {
+				if selected == models.WeekdayName(day) {
+					rule.ByDay = append(rule.ByDay, day)
+				}
+			}
+		}
+		if len(rule.ByDay) == 0 {
+			return nil, fmt.Errorf("请选择每周重复的日期")
+		}
+	case repeatMonthly:
+		n, err := strconv.Atoi(strings.TrimSpace(e.monthDay.Text))
+		if err != nil || n < 1 || n > 31 {
+			return nil, fmt.Errorf("每月的日期必须在 1 到 31 之间")
+		}
+		rule = &models.RecurrenceRule{Freq: models.FreqMonthly, Interval: interval, MonthDay: n}
+	}
+	return rule, rule.Validate()
+}
+
+// ruleString 返回用于比较的规则文本，nil 表示不重复
+func ruleString(rule *models.RecurrenceRule) string {
+	if rule == nil {
+		return ""
+	}
+	return rule.String()
+}
+
+// showScopeDialog 询问重复任务的修改范围：仅此任务、以后所有任务或取消。
+// 按 Esc 等方式关闭对话框时与取消相同，调用 cancel 且不保存任何修改
+func showScopeDialog(w fyne.Window, apply func(future bool), cancel func()) dialog.Dialog {
+	chosen := false
+	var scope *dialog.CustomDialog
+	choose := func(future bool) func() {
+		return func() {
+			chosen = true
+			scope.Hide()
+			apply(future)
+		}
+	}
+
+	future := widget.NewButton("以后所有任务", choose(true))
+	future.Importance = widget.HighImportance
+	scope = dialog.NewCustomWithoutButtons("修改重复任务", widget.NewLabel("是否同时修改以后所有的重复？"), w)
+	scope.SetButtons([]fyne.CanvasObject{
+		widget.NewButton("取消", scope.Hide),
+		widget.NewButton("仅此任务", choose(false)),
+		future,
+	})
+	scope.SetOnClosed(func() {
+		if !chosen {
+			cancel()
+		}
+	})
+	scope.Show()
+	return scope
+}
@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+)
+
+func TestScopeDialog(t *testing.T) {
+	test.NewApp()
+
+	tests := []struct {
+		button    string
+		applied   []bool
+		cancelled bool
+	}{
+		{"仅此任务", []bool{false}, false},
+		{"以后所有任务", []bool{true}, false},
+		{"取消", nil, true},
+		{"", nil, true}, // 不选择直接关闭，例如按 Esc
+	}
+	for _, tt := range tests {
+		w := test.NewWindow(nil)
+		var applied []bool
+		cancelled := false
+		scope := showScopeDialog(w, func(future bool) { applied = append(applied, future) }, func() { cancelled = true })
+
+		if tt.button == "" {
+			scope.Hide()
+		} else {
+			tapButton(t, w, tt.button)
+		}
+		if len(applied) != len(tt.applied) || (len(applied) > 0 && applied[0] != tt.applied[0]) || cancelled != tt.cancelled {
+			t.Errorf("%q: applied = %v, cancelled = %v, want %v, %v", tt.button, applied, cancelled, tt.applied, tt.cancelled)
+		}
+		w.Close()
+	}
+}
+
+// tapButton 点击窗口最上层对话框中文字为 text 的按钮
+func tapButton(t *testing.T, w fyne.Window, text string) {
+	t.Helper()
+	pop, ok := w.Canvas().Overlays().Top().(*widget.PopUp)
+	if !ok {
+		t.Fatal("no dialog shown")
+	}
+
+	var find func(o fyne.CanvasObject) *widget.Button
+	find = func(o fyne.CanvasObject) *widget.Button {
+		switch o := o.(type) {
+		case *widget.Button:
+			if o.Text == text {
+				return o
+			}
+		case *fyne.Container:
+			for _, child := range o.Objects {
+				if b := find(child); b != nil {
+					return b
+				}
+			}
+		}
+		return nil
+	}
+	button := find(pop.Content)
+	if button == nil {
+		t.Fatalf("button %q not found", text)
+	}
+	test.Tap(button)
+}
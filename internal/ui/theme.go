@@ -0,0 +1,145 @@
+package ui
+
+import (
+	"TodoList/internal/config"
+	"fmt"
+	"hash/fnv"
+	"image/color"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+)
+
+// 应用自定义的颜色名称
+const (
+	colorNameTimerOverlay    fyne.ThemeColorName = "timerOverlay"
+	colorNameTodoColumn      fyne.ThemeColorName = "todoColumn"
+	colorNameDoingColumn     fyne.ThemeColorName = "doingColumn"
+	colorNameDoneColumn      fyne.ThemeColorName = "doneColumn"
+	colorNameCancelledColumn fyne.ThemeColorName = "cancelledColumn"
+	colorNamePriorityLow     fyne.ThemeColorName = "priorityLow"
+	colorNamePriorityMedium  fyne.ThemeColorName = "priorityMedium"
+	colorNamePriorityHigh    fyne.ThemeColorName = "priorityHigh"
+)
+
+// 自定义颜色在浅色和深色模式下的取值
+var appPalette = map[fyne.ThemeColorName][2]color.Color{
+	colorNameTimerOverlay:    {color.NRGBA{R: 255, G: 255, B: 255, A: 180}, color.NRGBA{R: 30, G: 30, B: 30, A: 200}},
+	colorNameTodoColumn:      {color.NRGBA{R: 240, G: 248, B: 255, A: 255}, color.NRGBA{R: 32, G: 42, B: 56, A: 255}},
+	colorNameDoingColumn:     {color.NRGBA{R: 255, G: 250, B: 240, A: 255}, color.NRGBA{R: 56, G: 48, B: 30, A: 255}},
+	colorNameDoneColumn:      {color.NRGBA{R: 240, G: 255, B: 240, A: 255}, color.NRGBA{R: 30, G: 50, B: 36, A: 255}},
+	colorNameCancelledColumn: {color.NRGBA{R: 255, G: 240, B: 240, A: 255}, color.NRGBA{R: 56, G: 32, B: 32, A: 255}},
+	colorNamePriorityLow:     {color.NRGBA{R: 120, G: 160, B: 120, A: 255}, color.NRGBA{R: 90, G: 130, B: 90, A: 255}},
+	colorNamePriorityMedium:  {color.NRGBA{R: 230, G: 160, B: 40, A: 255}, color.NRGBA{R: 200, G: 135, B: 30, A: 255}},
+	colorNamePriorityHigh:    {color.NRGBA{R: 220, G: 60, B: 50, A: 255}, color.NRGBA{R: 190, G: 55, B: 45, A: 255}},
+}
+
+// 标签颜色，同一个标签总是使用同一种颜色
+var tagColors = []color.Color{
+	color.NRGBA{R: 66, G: 133, B: 244, A: 255},
+	color.NRGBA{R: 15, G: 157, B: 88, A: 255},
+	color.NRGBA{R: 171, G: 71, B: 188, A: 255},
+	color.NRGBA{R: 0, G: 150, B: 136, A: 255},
+	color.NRGBA{R: 239, G: 108, B: 0, A: 255},
+	color.NRGBA{R: 121, G: 85, B: 72, A: 255},
+	color.NRGBA{R: 233, G: 30, B: 99, A: 255},
+	color.NRGBA{R: 96, G: 125, B: 139, A: 255},
+}
+
+// tagColor 根据标签名称选择颜色，不区分大小写
+func tagColor(tag string) color.Color {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(tag)))
+	return tagColors[h.Sum32()%uint32(len(tagColors))]
+}
+
+// appTheme 根据 config.ThemeConfig 生成的主题
+type appTheme struct {
+	mode   string
+	scale  float32     // 字体相对默认大小的缩放比例
+	accent color.Color // 为 nil 时使用默认主题色
+}
+
+func newAppTheme(cfg config.ThemeConfig) *appTheme {
+	t := &appTheme{
+		mode:  cfg.EffectiveMode(),
+		scale: 1,
+	}
+
+	if cfg.FontSize > 0 {
+		t.scale = float32(cfg.FontSize) / theme.DefaultTheme().Size(theme.SizeNameText)
+	}
+	if accent, err := parseHexColor(cfg.AccentColor); err == nil {
+		t.accent = accent
+	}
+	return t
+}
+
+func (t *appTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant) color.Color {
+	switch t.mode {
+	case config.ThemeModeDark:
+		variant = theme.VariantDark
+	case config.ThemeModeLight:
+		variant = theme.VariantLight
+	}
+
+	if colors, ok := appPalette[name]; ok {
+		if variant == theme.VariantDark {
+			return colors[1]
+		}
+		return colors[0]
+	}
+
+	if t.accent != nil && name == theme.ColorNamePrimary {
+		return t.accent
+	}
+	return theme.DefaultTheme().Color(name, variant)
+}
+
+func (t *appTheme) Font(style fyne.TextStyle) fyne.Resource {
+	return theme.DefaultTheme().Font(style)
+}
+
+func (t *appTheme) Icon(name fyne.ThemeIconName) fyne.Resource {
+	return theme.DefaultTheme().Icon(name)
+}
+
+func (t *appTheme) Size(name fyne.ThemeSizeName) float32 {
+	size := theme.DefaultTheme().Size(name)
+	switch name {
+	case theme.SizeNameText, theme.SizeNameHeadingText, theme.SizeNameSubHeadingText, theme.SizeNameCaptionText:
+		return size * t.scale
+	}
+	return size
+}
+
+// ApplyTheme 根据配置设置应用主题
+func ApplyTheme(app fyne.App, cfg config.ThemeConfig) {
+	app.Settings().SetTheme(newAppTheme(cfg))
+}
+
+// scaledTextSize 按当前主题的字体大小缩放固定字号
+func scaledTextSize(base float32) float32 {
+	return base * theme.TextSize() / theme.DefaultTheme().Size(theme.SizeNameText)
+}
+
+// parseHexColor 解析 #RRGGBB 或 #RRGGBBAA 格式的颜色
+func parseHexColor(s string) (color.Color, error) {
+	c := color.NRGBA{A: 255}
+	switch len(s) {
+	case 7:
+		_, err := fmt.Sscanf(s, "#%02x%02x%02x", &c.R, &c.G, &c.B)
+		return c, err
+	case 9:
+		_, err := fmt.Sscanf(s, "#%02x%02x%02x%02x", &c.R, &c.G, &c.B, &c.A)
+		return c, err
+	}
+	return nil, fmt.Errorf("无效的颜色: %q", s)
+}
+
+// formatHexColor 将颜色转换为 #RRGGBB 格式
+func formatHexColor(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B)
+}
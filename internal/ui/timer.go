@@ -1,131 +1,68 @@
 package ui
 
 import (
+	"TodoList/internal/audio"
+	"TodoList/internal/models"
 	"TodoList/internal/storage"
+	"TodoList/internal/timer"
+	"database/sql"
+	"errors"
 	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
-	"github.com/faiface/beep/effects"
 	"time"
 
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/widget"
-	"github.com/faiface/beep"
-	"github.com/faiface/beep/speaker"
-	"github.com/faiface/beep/wav"
 	"image/color"
-	"os"
+	"strconv"
+	"strings"
 	"sync"
 )
 
-// PomodoroTimer 表示一个番茄钟计时器
+// PomodoroTimer 表示一个番茄钟计时器，计时逻辑由 timer.Engine 负责
 type PomodoroTimer struct {
-	workDuration      time.Duration       // 工作时长
-	breakDuration     time.Duration       // 休息时长
-	longBreakDuration time.Duration       // 长休息时长
-	isWorking         bool                // 是否处于工作状态
-	isRunning         bool                // 是否正在运行
-	remainingTime     time.Duration       // 剩余时间
-	onTick            func(time.Duration) // 计时回调函数
-	onComplete        func()              // 完成回调函数
+	engine     *timer.Engine       // 计时引擎
+	onTick     func(time.Duration) // 计时回调函数
+	onComplete func()              // 完成回调函数
 
 	// UI 组件
 	container      *fyne.Container
+	overlay        *canvas.Rectangle // 背景图片上的半透明遮罩
 	nameLabel      *canvas.Text
 	timeLabel      *canvas.Text
 	startButton    *widget.Button
 	resetButton    *widget.Button
+	skipButton     *widget.Button
 	statusLabel    *canvas.Text
 	countLabel     *canvas.Text   // 显示完成的番茄钟数量
 	settingsButton *widget.Button // 设置按钮
+	taskLabel      *canvas.Text   // 显示关联的任务
+	taskRow        *fyne.Container
 
 	// 新增字段
-	pomodoroCount           int    // 完成的番茄钟数量
-	pomodorosUntilLongBreak int    // 到长休息还需要的番茄钟数
-	name                    string // 添加名称字段
-	SetDeleteCallback       func() // 用于设置删除回调
-	onDelete                func() // 删除回调函数
-	onSave                  func()
-	deleteBtn               *widget.Button    // 删除按钮
-	db                      *storage.Database // 添加数据库字段
-}
-type SoundEffect int
-
-const (
-	SoundWorkComplete SoundEffect = iota
-	SoundBreakComplete
-	SoundLongBreakComplete
-)
-
-// 添加全局变量用于音频初始化
-var (
-	audioOnce    sync.Once
-	soundBuffers map[SoundEffect]*beep.Buffer
-	volume       float64 = 1.0
-)
-
-// 初始化音频系统
-func initAudio() error {
-	soundBuffers = make(map[SoundEffect]*beep.Buffer)
-
-	sounds := map[SoundEffect]string{
-		SoundWorkComplete:      "assets/work_complete.wav",
-		SoundBreakComplete:     "assets/break_complete.wav",
-		SoundLongBreakComplete: "assets/long_break_complete.wav",
-	}
-
-	var format beep.Format
-	for effect, path := range sounds {
-		f, err := os.Open(path)
-		if err != nil {
-			return err
-		}
-
-		streamer, fmt, err := wav.Decode(f)
-		if err != nil {
-			f.Close()
-			return err
-		}
-
-		if format.SampleRate == 0 {
-			format = fmt
-			if err := speaker.Init(format.SampleRate, format.SampleRate.N(time.Second/10)); err != nil {
-				return err
-			}
-		}
-
-		buffer := beep.NewBuffer(fmt)
-		buffer.Append(streamer)
-		soundBuffers[effect] = buffer
-
-		streamer.Close()
-		f.Close()
-	}
-
-	return nil
-}
-
-// 播放指定的音效
-func playSound(effect SoundEffect) {
-	if buffer, ok := soundBuffers[effect]; ok {
-		streamer := buffer.Streamer(0, buffer.Len())
-
-		// 创建音量控制器
-		volumeCtrl := &effects.Volume{
-			Streamer: streamer,
-			Base:     2,
-			Volume:   volume,
-			Silent:   false,
-		}
-
-		speaker.Play(volumeCtrl)
-	}
-}
-
-// 设置音量
-func setVolume(v float64) {
-	volume = v
+	name              string // 添加名称字段
+	SetDeleteCallback func() // 用于设置删除回调
+	onDelete          func() // 删除回调函数
+	onSave            func()
+	onRecordSaved     func(*models.PomodoroRecord) // 工作时段写入数据库后的回调
+	deleteBtn         *widget.Button               // 删除按钮
+	db                *storage.Database            // 添加数据库字段
+	task              *models.Task                 // 关联的任务
+	configID          int64                        // 对应的 timer_configs 记录，用于保存运行状态
+	config            *models.TimerConfig          // 保存的配置，设置窗口修改后由 onSave 写入数据库
+	prompt            *dialog.ConfirmDialog        // 等待开始下一阶段的提示
+	player            *audio.Player                // 提示音播放器，为 nil 时不播放
+	window            fyne.Window                  // 显示提示的窗口
+
+	// 阶段正常结束后记下结束的阶段，等下一阶段开始时一起通知
+	onPhaseEnded func(ended, next timer.Phase, waiting bool)
+	ended        *timer.Phase
+
+	mu sync.Mutex // 保护 task、name 和 config，计时协程、托盘和本地接口也会读取
 }
 
 // 定义颜色常量
@@ -136,9 +73,6 @@ var (
 	borderColor          = color.NRGBA{R: 200, G: 200, B: 200, A: 255} // 不透明边框
 	buttonPrimaryColor   = color.NRGBA{R: 255, G: 64, B: 129, A: 255}  // 粉红色
 	buttonSecondaryColor = color.NRGBA{R: 68, G: 138, B: 255, A: 255}  // 蓝色
-	textColor            = color.NRGBA{R: 40, G: 40, B: 40, A: 255}    // 深灰色文本
-	timeColor            = color.NRGBA{R: 25, G: 25, B: 25, A: 255}    // 更深的时间文本
-	countColor           = color.NRGBA{R: 80, G: 80, B: 80, A: 255}    // 较浅的计数文本
 )
 
 // 定义背景图片路径
@@ -148,17 +82,12 @@ const (
 	longBreakBgPath = "assets/backgrounds/long_break.jpg"
 )
 
-// NewPomodoroTimer 创建一个新的番茄钟计时器
-func NewPomodoroTimer(name string, workDuration, breakDuration, longBreakDuration time.Duration, db *storage.Database) *PomodoroTimer {
+// NewPomodoroTimer 创建一个新的番茄钟计时器，clock 为 nil 时使用系统时钟
+func NewPomodoroTimer(name string, cfg timer.Config, clock timer.Clock, db *storage.Database) *PomodoroTimer {
 	p := &PomodoroTimer{
-		name:                    name,
-		workDuration:            workDuration,
-		breakDuration:           breakDuration,
-		longBreakDuration:       longBreakDuration,
-		isWorking:               true,
-		remainingTime:           workDuration,
-		pomodorosUntilLongBreak: 4,
-		db:                      db,
+		name:   name,
+		engine: timer.NewEngine(cfg, clock),
+		db:     db,
 	}
 
 	// 创建背景图片
@@ -168,24 +97,32 @@ func NewPomodoroTimer(name string, workDuration, breakDuration, longBreakDuratio
 	background.FillMode = canvas.ImageFillStretch
 
 	// 创建半透明遮罩，使背景不那么显眼
-	overlay := canvas.NewRectangle(color.NRGBA{R: 255, G: 255, B: 255, A: 180})
-	overlay.CornerRadius = 20
+	p.overlay = canvas.NewRectangle(theme.Color(colorNameTimerOverlay))
+	p.overlay.CornerRadius = 20
 
-	// 初始化 UI 组件
-	p.timeLabel = canvas.NewText(formatDuration(workDuration), timeColor)
+	// 初始化 UI 组件，颜色和字号由 applyTheme 设置
+	p.timeLabel = canvas.NewText(formatDuration(cfg.Work), nil)
 	p.timeLabel.TextStyle = fyne.TextStyle{Bold: true}
-	p.timeLabel.TextSize = 32
 	p.timeLabel.Alignment = fyne.TextAlignCenter
 
-	p.statusLabel = canvas.NewText(name, textColor)
+	p.statusLabel = canvas.NewText(name, nil)
 	p.statusLabel.TextStyle = fyne.TextStyle{Bold: true}
-	p.statusLabel.TextSize = 20
 	p.statusLabel.Alignment = fyne.TextAlignCenter
 
-	p.countLabel = canvas.NewText("已完成: 0 个番茄钟", countColor)
-	p.countLabel.TextSize = 16
+	p.countLabel = canvas.NewText("已完成: 0 个番茄钟", nil)
 	p.countLabel.Alignment = fyne.TextAlignCenter
 
+	p.taskLabel = canvas.NewText("", nil)
+
+	// 取消关联任务的按钮
+	clearTaskBtn := widget.NewButtonWithIcon("", theme.ContentClearIcon(), func() {
+		p.SetTask(nil)
+	})
+	clearTaskBtn.Importance = widget.LowImportance
+
+	p.taskRow = container.NewHBox(layout.NewSpacer(), p.taskLabel, clearTaskBtn, layout.NewSpacer())
+	p.taskRow.Hide()
+
 	// 创建删除按钮
 	p.deleteBtn = widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
 		if p.onDelete != nil {
@@ -195,22 +132,15 @@ func NewPomodoroTimer(name string, workDuration, breakDuration, longBreakDuratio
 	p.deleteBtn.Importance = widget.HighImportance
 
 	// 创建主要控制按钮并设置样式
-	p.startButton = widget.NewButtonWithIcon("��始", theme.MediaPlayIcon(), func() {
-		if p.isRunning {
-			p.Stop()
-			p.startButton.SetIcon(theme.MediaPlayIcon())
-			p.startButton.SetText("开始")
-		} else {
-			p.Start()
-			p.startButton.SetIcon(theme.MediaPauseIcon())
-			p.startButton.SetText("停止")
-		}
-	})
+	p.startButton = widget.NewButtonWithIcon("开始", theme.MediaPlayIcon(), p.toggleTimer)
 	p.startButton.Importance = widget.HighImportance
 
 	p.resetButton = widget.NewButtonWithIcon("重置", theme.MediaReplayIcon(), p.Reset)
 	p.resetButton.Importance = widget.MediumImportance
 
+	p.skipButton = widget.NewButtonWithIcon("跳过", theme.MediaSkipNextIcon(), p.Toggle)
+	p.skipButton.Importance = widget.MediumImportance
+
 	p.settingsButton = widget.NewButtonWithIcon("设置", theme.SettingsIcon(), p.showSettings)
 	p.settingsButton.Importance = widget.MediumImportance
 
@@ -224,12 +154,14 @@ func NewPomodoroTimer(name string, workDuration, breakDuration, longBreakDuratio
 	controls := container.NewHBox(
 		p.startButton,
 		p.resetButton,
+		p.skipButton,
 		p.settingsButton,
 	)
 
 	// 创建内容容器
 	content := container.NewVBox(
 		topBar,
+		p.taskRow,
 		container.NewPadded(p.timeLabel),
 		container.NewPadded(p.countLabel),
 		controls,
@@ -241,42 +173,246 @@ func NewPomodoroTimer(name string, workDuration, breakDuration, longBreakDuratio
 	// 创建主容器，注意层次顺序
 	p.container = container.NewMax(
 		background, // 最底层：背景图片
-		overlay,    // 中间层：��透明遮罩
+		p.overlay,  // 中间层：半透明遮罩
 		//border,        // 上层：边框
 		paddedContent, // 最上层：内容
 	)
 
-	// 设置回调
-	p.SetOnTick(func(d time.Duration) {
-		p.timeLabel.Text = formatDuration(d)
+	p.applyTheme()
+	p.engine.Subscribe(p.handleEvent)
+
+	return p
+}
+
+// applyTheme 根据当前主题设置卡片的颜色和字号
+func (p *PomodoroTimer) applyTheme() {
+	foreground := theme.Color(theme.ColorNameForeground)
+
+	p.overlay.FillColor = theme.Color(colorNameTimerOverlay)
+	p.timeLabel.Color = foreground
+	p.timeLabel.TextSize = scaledTextSize(32)
+	p.statusLabel.Color = foreground
+	p.statusLabel.TextSize = scaledTextSize(20)
+	p.countLabel.Color = theme.Color(theme.ColorNamePlaceHolder)
+	p.countLabel.TextSize = scaledTextSize(16)
+	p.taskLabel.Color = foreground
+	p.taskLabel.TextSize = theme.TextSize()
+
+	p.container.Refresh()
+}
+
+// handleEvent 根据计时引擎的事件记录工作时段并更新界面
+// 事件可能来自计时协程，数据库操作直接执行，界面更新交给 runOnUI 按顺序执行
+func (p *PomodoroTimer) handleEvent(ev timer.Event) {
+	switch ev.Type {
+	case timer.EventStateChanged, timer.EventPhaseStarted:
+		p.saveState()
+	case timer.EventPhaseCompleted, timer.EventPhaseInterrupted:
+		p.saveRecord(ev)
+	}
+
+	runOnUI(func() { p.showEvent(ev) })
+}
+
+// showEvent 将事件反映到界面上
+func (p *PomodoroTimer) showEvent(ev timer.Event) {
+	switch ev.Type {
+	case timer.EventTick:
+		if p.onTick != nil {
+			p.onTick(ev.Remaining)
+		}
+		p.timeLabel.Text = formatDuration(ev.Remaining)
 		p.timeLabel.Refresh()
-	})
 
-	p.SetOnComplete(func() {
-		if p.isWorking {
-			p.statusLabel.Text = "休息时间"
-			background := canvas.NewImageFromFile(breakBgPath)
-			background.Resize(fyne.NewSize(300, 200)) // 设置合适的大小
-			background.FillMode = canvas.ImageFillStretch
-		} else {
-			p.statusLabel.Text = "工作时间"
-			background := canvas.NewImageFromFile(workBgPath)
-			background.Resize(fyne.NewSize(300, 200)) // 设置合适的大小
-			background.FillMode = canvas.ImageFillStretch
+	case timer.EventStateChanged:
+		p.timeLabel.Text = formatDuration(ev.Remaining)
+		p.timeLabel.Refresh()
+		p.updateStartButton(ev.State == models.StateRunning)
+		if ev.State == models.StateRunning {
+			p.hideStartPrompt()
+		}
+
+	case timer.EventPhaseCompleted:
+		// 补记的离线阶段只写入记录，不再提示
+		if ev.Replayed {
+			break
+		}
+		if p.onComplete != nil {
+			p.onComplete()
+		}
+		ended := ev.Phase
+		p.ended = &ended
+		// 播放提示音
+		go p.playNotificationSound(ev.Phase)
+
+	case timer.EventPhaseStarted:
+		p.showPhase(ev.Phase)
+		p.countLabel.Text = fmt.Sprintf("已完成: %d 个番茄钟", ev.Completed)
+		p.countLabel.Refresh()
+		p.timeLabel.Text = formatDuration(ev.Remaining)
+		p.timeLabel.Refresh()
+		p.updateStartButton(ev.State == models.StateRunning)
+		if p.ended != nil {
+			if p.onPhaseEnded != nil {
+				p.onPhaseEnded(*p.ended, ev.Phase, ev.State != models.StateRunning)
+			}
+			p.ended = nil
+		}
+
+	case timer.EventPhaseWaiting:
+		if !ev.Replayed {
+			p.showStartPrompt(ev.Phase)
+		}
+	}
+}
+
+// showStartPrompt 没有开启自动开始时，提示用户开始下一阶段
+func (p *PomodoroTimer) showStartPrompt(phase timer.Phase) {
+	p.hideStartPrompt()
+
+	title, message := "开始工作", "休息结束，开始下一个番茄钟吗？"
+	if phase.IsBreak() {
+		title, message = "开始休息", "工作时间结束，开始休息吗？"
+	}
+
+	// 对话框的回调在界面事件中执行，prompt 只在 runOnUI 中修改
+	var prompt *dialog.ConfirmDialog
+	prompt = dialog.NewConfirm(title, p.Name()+": "+message, func(ok bool) {
+		runOnUI(func() {
+			if p.prompt == prompt {
+				p.prompt = nil
+			}
+		})
+		if ok {
+			p.Start()
 		}
-		background.Refresh()
+	}, p.window)
+	prompt.SetConfirmText(title)
+	prompt.SetDismissText("稍后")
+	p.prompt = prompt
+	prompt.Show()
+}
+
+// hideStartPrompt 通过按钮或接口开始计时后关闭提示
+func (p *PomodoroTimer) hideStartPrompt() {
+	if p.prompt != nil {
+		p.prompt.Hide()
+		p.prompt = nil
+	}
+}
+
+// showPhase 根据阶段切换状态文字和背景
+func (p *PomodoroTimer) showPhase(phase timer.Phase) {
+	bgPath := workBgPath
+	switch phase {
+	case timer.PhaseShortBreak:
+		bgPath = breakBgPath
+	case timer.PhaseLongBreak:
+		bgPath = longBreakBgPath
+	}
+	p.statusLabel.Text = phaseNames[phase]
+	p.statusLabel.Refresh()
+
+	if bg, ok := p.container.Objects[0].(*canvas.Image); ok {
+		bg.File = bgPath
+		bg.Refresh()
+	}
+}
+
+// updateStartButton 根据运行状态切换开始按钮的图标和文字
+func (p *PomodoroTimer) updateStartButton(running bool) {
+	if running {
+		p.startButton.SetIcon(theme.MediaPauseIcon())
+		p.startButton.SetText("停止")
+	} else {
+		p.startButton.SetIcon(theme.MediaPlayIcon())
+		p.startButton.SetText("开始")
+	}
+}
+
+// saveRecord 将工作时段写入 pomodoro_records
+func (p *PomodoroTimer) saveRecord(ev timer.Event) {
+	if p.db == nil {
+		return
+	}
+
+	var taskID int64
+	if task := p.Task(); task != nil {
+		taskID = task.ID
+	}
+
+	record := ev.Record(taskID)
+	if record == nil {
+		return
+	}
+
+	if err := p.db.SavePomodoroRecord(record); err != nil {
+		fmt.Println("Error saving pomodoro record:", err)
+		return
+	}
+
+	if p.onRecordSaved != nil {
+		p.onRecordSaved(record)
+	}
+}
+
+// restoreState 读取 configID 保存的运行状态并恢复，之后的状态变化都会保存
+func (p *PomodoroTimer) restoreState(configID int64) {
+	p.configID = configID
+	if p.db == nil {
+		return
+	}
+
+	state, err := p.db.GetTimerState(configID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	if err != nil {
+		fmt.Println("Error loading timer state:", err)
+		return
+	}
+
+	if state.TaskID != 0 {
+		if task, err := p.db.GetTaskByID(state.TaskID); err == nil {
+			p.SetTask(task)
+		}
+	}
+
+	p.engine.Restore(*state)
+	phase, completed := p.engine.Phase(), p.engine.Completed()
+	runOnUI(func() {
+		p.showPhase(phase)
+		p.countLabel.Text = fmt.Sprintf("已完成: %d 个番茄钟", completed)
+		p.countLabel.Refresh()
 	})
+}
 
-	return p
+// saveState 保存当前的运行状态
+func (p *PomodoroTimer) saveState() {
+	if p.db == nil || p.configID == 0 {
+		return
+	}
+
+	state := p.engine.Runtime()
+	state.TimerConfigID = p.configID
+	if task := p.Task(); task != nil {
+		state.TaskID = task.ID
+	}
+	if err := p.db.SaveTimerState(&state); err != nil {
+		fmt.Println("Error saving timer state:", err)
+	}
+}
+
+// close 停止计时但保留保存的运行状态，用于切换日期时丢弃界面上的计时器
+func (p *PomodoroTimer) close() {
+	p.engine.Close()
 }
 
 func (p *PomodoroTimer) toggleTimer() {
-	if p.isRunning {
+	if p.IsRunning() {
 		p.Stop()
-		p.startButton.SetText("开始")
 	} else {
 		p.Start()
-		p.startButton.SetText("停止")
 	}
 }
 
@@ -289,96 +425,76 @@ func formatDuration(d time.Duration) string {
 
 // Start 开始计时
 func (p *PomodoroTimer) Start() {
-	if p.isRunning {
-		return
-	}
-	p.isRunning = true
-
-	go func() {
-		ticker := time.NewTicker(time.Second)
-		defer ticker.Stop()
-
-		for p.isRunning && p.remainingTime > 0 {
-			<-ticker.C
-			p.remainingTime -= time.Second
-			if p.onTick != nil {
-				p.onTick(p.remainingTime)
-			}
-
-			if p.remainingTime <= 0 {
-				if p.onComplete != nil {
-					p.onComplete()
-				}
-				p.Toggle()
-			}
-		}
-	}()
+	p.engine.Start()
 }
 
 // Stop 停止计时
 func (p *PomodoroTimer) Stop() {
-	p.isRunning = false
+	p.engine.Pause()
 }
 
 // Reset 重置计时器
 func (p *PomodoroTimer) Reset() {
-	p.Stop()
-	if p.isWorking {
-		p.remainingTime = p.workDuration
-	} else {
-		p.remainingTime = p.breakDuration
-	}
+	p.engine.Reset()
 }
 
 // Toggle 切换工作/休息状态
 func (p *PomodoroTimer) Toggle() {
-	if p.isWorking {
-		p.pomodoroCount++
-		p.countLabel.Text = fmt.Sprintf("已完成: %d 个番茄钟", p.pomodoroCount)
-		p.countLabel.Refresh()
+	p.engine.Skip()
+}
 
-		if p.pomodoroCount%p.pomodorosUntilLongBreak == 0 {
-			p.remainingTime = p.longBreakDuration
-			p.statusLabel.Text = "长休息时间"
-			// 设置长休息背景
-			if bg, ok := p.container.Objects[0].(*canvas.Image); ok {
-				bg.File = longBreakBgPath
-				bg.Refresh()
-			}
+// SetTask 设置计时器关联的任务，之后的工作时段都会记录到该任务下
+func (p *PomodoroTimer) SetTask(task *models.Task) {
+	p.mu.Lock()
+	p.task = task
+	p.mu.Unlock()
+
+	runOnUI(func() {
+		if task == nil {
+			p.taskLabel.Text = ""
+			p.taskRow.Hide()
 		} else {
-			p.remainingTime = p.breakDuration
-			p.statusLabel.Text = "休息时间"
-			// 设置休息背景
-			if bg, ok := p.container.Objects[0].(*canvas.Image); ok {
-				bg.File = breakBgPath
-				bg.Refresh()
-			}
+			p.taskLabel.Text = "专注: " + task.Title
+			p.taskRow.Show()
 		}
-	} else {
-		p.remainingTime = p.workDuration
-		p.statusLabel.Text = "工作时间"
-		// 设置工作背景
-		if bg, ok := p.container.Objects[0].(*canvas.Image); ok {
-			bg.File = workBgPath
-			bg.Refresh()
-		}
-	}
-	p.statusLabel.Refresh()
-	p.isWorking = !p.isWorking
+		p.taskLabel.Refresh()
+	})
+	p.saveState()
+}
 
-	// 播放提示音
-	go p.playNotificationSound()
+// Task 返回计时器关联的任务
+func (p *PomodoroTimer) Task() *models.Task {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.task
+}
+
+// Name 返回计时器名称
+func (p *PomodoroTimer) Name() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.name
+}
+
+// savedConfig 返回保存的配置的副本
+func (p *PomodoroTimer) savedConfig() *models.TimerConfig {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	saved := *p.config
+	return &saved
+}
+
+// setSavedConfig 替换保存的配置，同时更新名称
+func (p *PomodoroTimer) setSavedConfig(cfg *models.TimerConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = cfg
+	p.name = cfg.Name
 }
 
 // SetOnTick 设置计时回调函数
 func (p *PomodoroTimer) SetOnTick(callback func(time.Duration)) {
-	p.onTick = func(d time.Duration) {
-		if callback != nil {
-			callback(d)
-		}
-		p.timeLabel.Text = formatDuration(d)
-		p.timeLabel.Refresh()
-	}
+	p.onTick = callback
 }
 
 // SetOnComplete 设置完成回调函数
@@ -388,38 +504,47 @@ func (p *PomodoroTimer) SetOnComplete(callback func()) {
 
 // IsWorking 返回是否处于工作状态
 func (p *PomodoroTimer) IsWorking() bool {
-	return p.isWorking
+	return p.engine.Phase() == timer.PhaseWork
 }
 
 // IsRunning 返回是否正在运行
 func (p *PomodoroTimer) IsRunning() bool {
-	return p.isRunning
+	return p.engine.State() == models.StateRunning
 }
 
 // GetRemainingTime 获取剩余时间
 func (p *PomodoroTimer) GetRemainingTime() time.Duration {
-	return p.remainingTime
+	return p.engine.Remaining()
 }
 
 // showSettings 显示设置窗口
 func (p *PomodoroTimer) showSettings() {
 	// 创建设置窗口
 	w := fyne.CurrentApp().NewWindow("番茄钟设置")
+	cfg := p.engine.Config()
+	saved := *p.savedConfig()
 
 	nameEntry := widget.NewEntry()
-	nameEntry.SetText(p.name)
+	nameEntry.SetText(p.Name())
 
 	workEntry := widget.NewEntry()
-	workEntry.SetText(fmt.Sprintf("%d", int(p.workDuration.Minutes())))
+	workEntry.SetText(fmt.Sprintf("%d", int(cfg.Work.Minutes())))
 
 	breakEntry := widget.NewEntry()
-	breakEntry.SetText(fmt.Sprintf("%d", int(p.breakDuration.Minutes())))
+	breakEntry.SetText(fmt.Sprintf("%d", int(cfg.ShortBreak.Minutes())))
 
 	longBreakEntry := widget.NewEntry()
-	longBreakEntry.SetText(fmt.Sprintf("%d", int(p.longBreakDuration.Minutes())))
+	longBreakEntry.SetText(fmt.Sprintf("%d", int(cfg.LongBreak.Minutes())))
 
+	// 长休息间隔和自动开始留空或跟随全局设置时不单独保存
 	pomodorosEntry := widget.NewEntry()
-	pomodorosEntry.SetText(fmt.Sprintf("%d", p.pomodorosUntilLongBreak))
+	pomodorosEntry.SetPlaceHolder(fmt.Sprintf("跟随全局设置 (%d)", cfg.LongBreakAfter))
+	if saved.LongBreakAfter != nil {
+		pomodorosEntry.SetText(fmt.Sprintf("%d", *saved.LongBreakAfter))
+	}
+
+	autoBreakSelect := newOverrideSelect(saved.AutoStartBreak)
+	autoPomodoroSelect := newOverrideSelect(saved.AutoStartPomodoro)
 
 	form := &widget.Form{
 		Items: []*widget.FormItem{
@@ -428,19 +553,39 @@ func (p *PomodoroTimer) showSettings() {
 			{Text: "休息时长(分钟)", Widget: breakEntry},
 			{Text: "长休息时长(分钟)", Widget: longBreakEntry},
 			{Text: "长休息间隔(番茄钟数)", Widget: pomodorosEntry},
+			{Text: "自动开始休息", Widget: autoBreakSelect},
+			{Text: "自动开始番茄钟", Widget: autoPomodoroSelect},
 		},
 		OnSubmit: func() {
-			// 保存设置
-			p.name = nameEntry.Text
-			p.workDuration = time.Duration(mustParseInt(workEntry.Text)) * time.Minute
-			p.breakDuration = time.Duration(mustParseInt(breakEntry.Text)) * time.Minute
-			p.longBreakDuration = time.Duration(mustParseInt(longBreakEntry.Text)) * time.Minute
-			p.pomodorosUntilLongBreak = int(mustParseInt(pomodorosEntry.Text))
-			p.statusLabel.Text = p.name
-			p.statusLabel.Refresh()
-			p.onSave()
+			saved.LongBreakAfter = nil
+			if text := strings.TrimSpace(pomodorosEntry.Text); text != "" {
+				n, err := strconv.Atoi(text)
+				if err != nil || n < 1 {
+					dialog.ShowError(fmt.Errorf("长休息间隔必须是正整数"), w)
+					return
+				}
+				saved.LongBreakAfter = &n
+			}
+
+			// 保存设置，引擎会结束当前时段并按新时长重置
+			saved.Name = nameEntry.Text
+			saved.WorkDuration = time.Duration(mustParseInt(workEntry.Text)) * time.Minute
+			saved.BreakDuration = time.Duration(mustParseInt(breakEntry.Text)) * time.Minute
+			saved.LongBreak = time.Duration(mustParseInt(longBreakEntry.Text)) * time.Minute
+			saved.AutoStartBreak = selectedOverride(autoBreakSelect)
+			saved.AutoStartPomodoro = selectedOverride(autoPomodoroSelect)
+			// 保存副本，再次提交表单时不会修改其他协程正在读取的配置
+			submitted := saved
+			p.setSavedConfig(&submitted)
+
+			runOnUI(func() {
+				p.statusLabel.Text = submitted.Name
+				p.statusLabel.Refresh()
+			})
+			if p.onSave != nil {
+				p.onSave()
+			}
 			p.container.Refresh()
-			p.Reset()
 			w.Close()
 		},
 	}
@@ -450,14 +595,50 @@ func (p *PomodoroTimer) showSettings() {
 	w.Show()
 }
 
-func (p *PomodoroTimer) playNotificationSound() {
-	// 根据当前状态播放不同的音效
-	if p.isWorking {
+// 单独设置的开关选项，第一项表示使用全局配置
+var overrideOptions = []string{"跟随全局设置", "开启", "关闭"}
+
+func newOverrideSelect(value *bool) *widget.Select {
+	sel := widget.NewSelect(overrideOptions, nil)
+	switch {
+	case value == nil:
+		sel.SetSelectedIndex(0)
+	case *value:
+		sel.SetSelectedIndex(1)
+	default:
+		sel.SetSelectedIndex(2)
+	}
+	return sel
+}
+
+// selectedOverride 返回选择的开关值，跟随全局设置时返回 nil
+func selectedOverride(sel *widget.Select) *bool {
+	switch sel.SelectedIndex() {
+	case 1:
+		v := true
+		return &v
+	case 2:
+		v := false
+		return &v
+	}
+	return nil
+}
+
+func (p *PomodoroTimer) playNotificationSound(phase timer.Phase) {
+	if p.player == nil {
+		return
+	}
+
+	// 根据结束的阶段播放不同的音效
+	switch phase {
+	case timer.PhaseWork:
 		// 工作时间结束，播放工作完成音效
-		playSound(SoundWorkComplete)
-	} else {
+		p.player.Play(audio.SoundWorkComplete)
+	case timer.PhaseLongBreak:
+		p.player.Play(audio.SoundLongBreakComplete)
+	default:
 		// 休息时间结束，播放休息完成音效
-		playSound(SoundBreakComplete)
+		p.player.Play(audio.SoundBreakComplete)
 	}
 }
 
@@ -469,3 +650,13 @@ func (p *PomodoroTimer) SetOnDelete(callback func()) {
 func (p *PomodoroTimer) SetOnSave(callback func()) {
 	p.onSave = callback
 }
+
+// SetOnRecordSaved 设置工作时段写入数据库后的回调
+func (p *PomodoroTimer) SetOnRecordSaved(callback func(*models.PomodoroRecord)) {
+	p.onRecordSaved = callback
+}
+
+// SetOnPhaseEnded 设置阶段正常结束后的回调，waiting 表示下一阶段等待手动开始
+func (p *PomodoroTimer) SetOnPhaseEnded(callback func(ended, next timer.Phase, waiting bool)) {
+	p.onPhaseEnded = callback
+}
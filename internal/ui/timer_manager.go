@@ -1,46 +1,64 @@
 package ui
 
 import (
+	"TodoList/internal/api"
+	"TodoList/internal/audio"
+	"TodoList/internal/config"
 	"TodoList/internal/models"
 	"TodoList/internal/storage"
+	"TodoList/internal/timer"
+	"database/sql"
+	"errors"
 	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 	"strconv"
+	"sync"
 	"time"
 )
 
 type TimerManager struct {
-	container   *fyne.Container
-	timers     []*PomodoroTimer
-	addButton  *widget.Button
-	db         *storage.Database
-	dateSelect *widget.Select
-	currentDate time.Time
+	container     *fyne.Container
+	timers        map[string][]*PomodoroTimer // 按日期保存已创建的计时器，切换日期后仍继续计时
+	addButton     *widget.Button
+	db            *storage.Database
+	datePicker    *DatePicker
+	currentDate   time.Time
+	onRecordSaved func(*models.PomodoroRecord) // 任一计时器写入工作时段后的回调
+	defaults      config.PomodoroConfig        // 全局番茄钟配置
+	player        *audio.Player                // 所有计时器共用的提示音播放器
+	notifier      Notifier                     // 阶段结束时发送桌面通知，为 nil 时不发送
+	clock         timer.Clock                  // 计时引擎使用的时钟，为 nil 时使用系统时钟
+	window        fyne.Window                  // 显示对话框的主窗口
+
+	mu sync.Mutex // 保护 timers、currentDate 和 defaults，本地接口会在其他协程中访问
+	// 界面事件和 runOnUI 都会重新排列计时器卡片，同一时间只允许一个更新
+	layoutMu sync.Mutex
+	// 创建计时器时持有，避免界面和本地接口为同一个配置各创建一个计时器
+	loadMu sync.Mutex
 }
 
-func NewTimerManager(db *storage.Database) *TimerManager {
+// NewTimerManager 创建番茄钟页，对话框显示在 window 中
+func NewTimerManager(db *storage.Database, defaults config.PomodoroConfig, player *audio.Player, notifier Notifier, window fyne.Window) *TimerManager {
 	tm := &TimerManager{
-		timers:      make([]*PomodoroTimer, 0),
+		timers:      make(map[string][]*PomodoroTimer),
 		db:          db,
 		currentDate: time.Now(),
+		defaults:    defaults,
+		player:      player,
+		notifier:    notifier,
+		window:      window,
 	}
 
 	tm.addButton = widget.NewButton("添加番茄钟", tm.showAddDialog)
 
-	dates, err := tm.getAvailableDates()
-	if err != nil {
-		dates = []string{time.Now().Format("2006-01-02")}
-	}
-
-	tm.dateSelect = widget.NewSelect(dates, tm.onDateSelected)
-	tm.dateSelect.SetSelected(time.Now().Format("2006-01-02"))
+	tm.datePicker = NewDatePicker(db.GetActiveDates, tm.onDateSelected)
 
 	toolbar := container.NewHBox(
 		widget.NewLabel("选择日期:"),
-		tm.dateSelect,
+		tm.datePicker.container,
 		tm.addButton,
 	)
 
@@ -54,63 +72,51 @@ func NewTimerManager(db *storage.Database) *TimerManager {
 	return tm
 }
 
-func (tm *TimerManager) getAvailableDates() ([]string, error) {
-	dates, err := tm.db.GetDistinctDates()
-	if err != nil {
-		return nil, err
-	}
-
-	today := time.Now().Format("2006-01-02")
-	hasToday := false
-	for _, date := range dates {
-		if date == today {
-			hasToday = true
-			break
-		}
-	}
-	if !hasToday {
-		dates = append([]string{today}, dates...)
-	}
-
-	return dates, nil
+func (tm *TimerManager) onDateSelected(selectedDate time.Time) {
+	tm.mu.Lock()
+	tm.currentDate = selectedDate
+	tm.mu.Unlock()
+	tm.loadDateConfigs(selectedDate)
 }
 
-func (tm *TimerManager) onDateSelected(dateStr string) {
-	selectedDate, err := time.Parse("2006-01-02", dateStr)
+// loadDateConfigs 显示 date 的计时器
+// 之前日期的计时器只是隐藏，正在运行的番茄钟继续计时并写入记录
+func (tm *TimerManager) loadDateConfigs(date time.Time) {
+	tm.loadMu.Lock()
+	err := tm.loadDate(date)
+	tm.loadMu.Unlock()
 	if err != nil {
-		dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
-		return
+		dialog.ShowError(err, tm.window)
 	}
-
-	tm.currentDate = selectedDate
-	defer tm.loadDateConfigs(selectedDate)
+	tm.updateLayout()
 }
 
-func (tm *TimerManager) loadDateConfigs(date time.Time) {
-	tm.timers = make([]*PomodoroTimer, 0)
+// loadDate 第一次打开 date 时从数据库创建这一天的计时器，调用时必须持有 loadMu
+func (tm *TimerManager) loadDate(date time.Time) error {
+	key := dateKey(date)
+	tm.mu.Lock()
+	_, loaded := tm.timers[key]
+	tm.mu.Unlock()
+	if loaded {
+		return nil
+	}
 
 	configs, err := tm.db.GetTimerConfigsByDate(date)
 	if err != nil {
-		dialog.ShowError(err, fyne.CurrentApp().Driver().AllWindows()[0])
-		return
+		return err
 	}
-
+	timers := make([]*PomodoroTimer, 0, len(configs))
 	for _, config := range configs {
-		timer := NewPomodoroTimer(
-			config.Name,
-			config.WorkDuration,
-			config.BreakDuration,
-			config.LongBreak,
-			tm.db,
-		)
-		currentTimer := timer
-		timer.SetOnDelete(func() {
-			tm.removeTimer(currentTimer)
-		})
-		tm.timers = append(tm.timers, timer)
+		timers = append(timers, tm.newTimer(config))
 	}
+	tm.mu.Lock()
+	tm.timers[key] = timers
+	tm.mu.Unlock()
+	return nil
+}
 
-	defer tm.updateLayout()
+func dateKey(date time.Time) string {
+	return date.Format("2006-01-02")
 }
 
 func (tm *TimerManager) saveTimerConfig(name string, work, break_, longBreak time.Duration) error {
@@ -119,7 +125,7 @@ func (tm *TimerManager) saveTimerConfig(name string, work, break_, longBreak tim
 		WorkDuration:  work,
 		BreakDuration: break_,
 		LongBreak:     longBreak,
-		Date:          tm.currentDate,
+		Date:          tm.date(),
 	}
 	return tm.db.SaveTimerConfig(config)
 }
@@ -128,17 +134,21 @@ func (tm *TimerManager) showAddDialog() {
 	fmt.Println("Opening add dialog")
 	w := fyne.CurrentApp().NewWindow("添加番茄钟")
 
+	tm.mu.Lock()
+	defaults := tm.defaults
+	tm.mu.Unlock()
+
 	nameEntry := widget.NewEntry()
 	nameEntry.SetPlaceHolder("番茄钟名称")
 
 	workEntry := widget.NewEntry()
-	workEntry.SetText("25")
+	workEntry.SetText(fmt.Sprintf("%d", int(defaults.WorkDuration.Minutes())))
 
 	breakEntry := widget.NewEntry()
-	breakEntry.SetText("5")
+	breakEntry.SetText(fmt.Sprintf("%d", int(defaults.ShortBreak.Minutes())))
 
 	longBreakEntry := widget.NewEntry()
-	longBreakEntry.SetText("15")
+	longBreakEntry.SetText(fmt.Sprintf("%d", int(defaults.LongBreak.Minutes())))
 
 	form := &widget.Form{
 		Items: []*widget.FormItem{
@@ -176,38 +186,25 @@ func (tm *TimerManager) showAddDialog() {
 				WorkDuration:  workDuration,
 				BreakDuration: breakDuration,
 				LongBreak:     longBreakDuration,
-				Date:          tm.currentDate,
+				Date:          tm.date(),
 			}
 
+			tm.loadMu.Lock()
 			err := tm.db.SaveTimerConfig(config)
 			if err != nil {
+				tm.loadMu.Unlock()
 				dialog.ShowError(fmt.Errorf("保存配置失败: %v", err), w)
 				return
 			}
 
 			fmt.Println("Creating new timer")
-			timer := NewPomodoroTimer(
-				nameEntry.Text,
-				workDuration,
-				breakDuration,
-				longBreakDuration,
-				tm.db,
-			)
-
-			timer.SetOnDelete(func() {
-				tm.removeTimer(timer)
-			})
-
-			tm.timers = append(tm.timers, timer)
+			tm.addTimer(tm.newTimer(config))
+			tm.loadMu.Unlock()
 			tm.updateLayout()
 
 			tm.container.Refresh()
 
 			w.Close()
-
-			dates, _ := tm.getAvailableDates()
-			tm.dateSelect.Options = dates
-			tm.dateSelect.Refresh()
 		},
 	}
 
@@ -216,28 +213,271 @@ func (tm *TimerManager) showAddDialog() {
 	w.Show()
 }
 
+// engineConfig 根据保存的配置和全局设置生成计时引擎配置
+func (tm *TimerManager) engineConfig(cfg *models.TimerConfig) timer.Config {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return timer.NewConfig(cfg, tm.defaults)
+}
+
+// ApplyPomodoroConfig 应用新的全局番茄钟配置
+// 仍使用旧默认时长的计时器会改用新的默认时长，没有单独设置的选项对所有计时器生效
+func (tm *TimerManager) ApplyPomodoroConfig(cfg config.PomodoroConfig) {
+	tm.mu.Lock()
+	old := tm.defaults
+	tm.defaults = cfg
+	tm.mu.Unlock()
+
+	for _, t := range tm.allTimers() {
+		tc := t.savedConfig()
+		if tc.WorkDuration == old.WorkDuration && tc.BreakDuration == old.ShortBreak && tc.LongBreak == old.LongBreak {
+			tc.WorkDuration = cfg.WorkDuration
+			tc.BreakDuration = cfg.ShortBreak
+			tc.LongBreak = cfg.LongBreak
+
+			if err := tm.db.UpdateTimerConfig(tc); err != nil {
+				fmt.Println("Error updating timer config:", err)
+			}
+			t.setSavedConfig(tc)
+		}
+		t.engine.UpdateConfig(tm.engineConfig(tc))
+	}
+}
+
+// saveTimer 保存计时器设置窗口中修改的配置，并按新配置重置当前阶段
+func (tm *TimerManager) saveTimer(timer *PomodoroTimer) {
+	cfg := timer.savedConfig()
+	if err := tm.db.UpdateTimerConfig(cfg); err != nil {
+		dialog.ShowError(fmt.Errorf("保存配置失败: %v", err), tm.window)
+		return
+	}
+	timer.engine.SetConfig(tm.engineConfig(cfg))
+}
+
+// applyTheme 将当前主题应用到所有计时器卡片
+func (tm *TimerManager) applyTheme() {
+	for _, t := range tm.allTimers() {
+		t.applyTheme()
+	}
+}
+
+// snapshot 返回当前日期计时器列表的副本，遍历时不必持有锁
+func (tm *TimerManager) snapshot() []*PomodoroTimer {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return append([]*PomodoroTimer(nil), tm.timers[dateKey(tm.currentDate)]...)
+}
+
+// allTimers 返回所有日期已创建的计时器
+func (tm *TimerManager) allTimers() []*PomodoroTimer {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	var timers []*PomodoroTimer
+	for _, list := range tm.timers {
+		timers = append(timers, list...)
+	}
+	return timers
+}
+
+func (tm *TimerManager) date() time.Time {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return tm.currentDate
+}
+
+// addTimer 将计时器加入它的配置所在的日期
+func (tm *TimerManager) addTimer(timer *PomodoroTimer) {
+	key := dateKey(timer.savedConfig().Date)
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.timers[key] = append(tm.timers[key], timer)
+}
+
+// newTimer 根据保存的配置创建计时器，并恢复上次的运行状态
+func (tm *TimerManager) newTimer(config *models.TimerConfig) *PomodoroTimer {
+	timer := NewPomodoroTimer(config.Name, tm.engineConfig(config), tm.clock, tm.db)
+	timer.config = config
+	timer.player = tm.player
+	timer.window = tm.window
+	tm.bindTimer(timer)
+	timer.restoreState(config.ID)
+	return timer
+}
+
+// bindTimer 为计时器设置管理器相关的回调
+func (tm *TimerManager) bindTimer(t *PomodoroTimer) {
+	t.SetOnDelete(func() {
+		tm.removeTimer(t)
+	})
+	t.SetOnSave(func() {
+		tm.saveTimer(t)
+	})
+	t.SetOnPhaseEnded(func(ended, next timer.Phase, waiting bool) {
+		tm.notifyPhaseEnded(t, ended, next, waiting)
+	})
+	t.SetOnRecordSaved(func(record *models.PomodoroRecord) {
+		if tm.onRecordSaved != nil {
+			tm.onRecordSaved(record)
+		}
+	})
+}
+
+// notifyPhaseEnded 按配置发送阶段结束的桌面通知
+func (tm *TimerManager) notifyPhaseEnded(t *PomodoroTimer, ended, next timer.Phase, waiting bool) {
+	tm.mu.Lock()
+	cfg := tm.defaults.Notifications
+	tm.mu.Unlock()
+
+	if tm.notifier == nil || !notifyEnabled(cfg, ended) {
+		return
+	}
+	tm.notifier.Notify(phaseNotification(t.Name(), ended, next, waiting, t.Task()))
+}
+
+// SetOnRecordSaved 设置工作时段写入数据库后的回调
+func (tm *TimerManager) SetOnRecordSaved(callback func(*models.PomodoroRecord)) {
+	tm.onRecordSaved = callback
+}
+
+// FocusTask 将任务关联到当前的番茄钟，优先选择正在运行的计时器
+func (tm *TimerManager) FocusTask(task *models.Task) {
+	timers := tm.snapshot()
+	if len(timers) == 0 {
+		dialog.ShowInformation("专注任务", "请先添加一个番茄钟", tm.window)
+		return
+	}
+
+	candidates := make([]*PomodoroTimer, 0)
+	for _, timer := range timers {
+		if timer.IsRunning() {
+			candidates = append(candidates, timer)
+		}
+	}
+	if len(candidates) == 0 {
+		candidates = timers
+	}
+
+	if len(candidates) == 1 {
+		candidates[0].SetTask(task)
+		return
+	}
+
+	// 有多个候选计时器时让用户选择
+	names := make([]string, len(candidates))
+	for i, timer := range candidates {
+		names[i] = fmt.Sprintf("%d. %s", i+1, timer.Name())
+	}
+	timerSelect := widget.NewSelect(names, nil)
+	timerSelect.SetSelectedIndex(0)
+
+	dialog.ShowCustomConfirm("选择番茄钟", "确定", "取消", timerSelect, func(ok bool) {
+		if !ok || timerSelect.SelectedIndex() < 0 {
+			return
+		}
+		candidates[timerSelect.SelectedIndex()].SetTask(task)
+	}, tm.window)
+}
+
+// ListTimers 返回当前日期的番茄钟状态，实现 api.Timers
+func (tm *TimerManager) ListTimers() ([]api.TimerStatus, error) {
+	timers := tm.snapshot()
+	statuses := make([]api.TimerStatus, 0, len(timers))
+	for _, t := range timers {
+		statuses = append(statuses, api.NewTimerStatus(t.Name(), t.engine, t.Task()))
+	}
+	return statuses, nil
+}
+
+func (tm *TimerManager) StartTimer(name string) (api.TimerStatus, error) {
+	return tm.controlTimer(name, (*PomodoroTimer).Start)
+}
+
+func (tm *TimerManager) PauseTimer(name string) (api.TimerStatus, error) {
+	return tm.controlTimer(name, (*PomodoroTimer).Stop)
+}
+
+func (tm *TimerManager) ResetTimer(name string) (api.TimerStatus, error) {
+	return tm.controlTimer(name, (*PomodoroTimer).Reset)
+}
+
+func (tm *TimerManager) controlTimer(name string, action func(*PomodoroTimer)) (api.TimerStatus, error) {
+	t, err := tm.timerByName(name)
+	if err != nil {
+		return api.TimerStatus{}, err
+	}
+	action(t)
+	return api.NewTimerStatus(t.Name(), t.engine, t.Task()), nil
+}
+
+// timerByName 查找当前日期的计时器，通过接口新建的配置会在这里加入界面
+func (tm *TimerManager) timerByName(name string) (*PomodoroTimer, error) {
+	if t := tm.findTimer(name); t != nil {
+		return t, nil
+	}
+
+	tm.loadMu.Lock()
+	defer tm.loadMu.Unlock()
+	// 当前日期可能还没有加载，或者等锁时已经创建了这个计时器
+	date := tm.date()
+	if err := tm.loadDate(date); err != nil {
+		return nil, err
+	}
+	if t := tm.findTimer(name); t != nil {
+		return t, nil
+	}
+
+	config, err := tm.db.GetTimerConfigByName(name, date)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, api.ErrTimerNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	timer := tm.newTimer(config)
+	tm.addTimer(timer)
+	runOnUI(tm.updateLayout)
+	return timer, nil
+}
+
+// findTimer 在当前日期的计时器中按名称查找
+func (tm *TimerManager) findTimer(name string) *PomodoroTimer {
+	for _, t := range tm.snapshot() {
+		if t.Name() == name {
+			return t
+		}
+	}
+	return nil
+}
+
 func (tm *TimerManager) removeTimer(timer *PomodoroTimer) {
-	err := tm.db.DeleteTimerConfig(timer.name, tm.currentDate)
+	err := tm.db.DeleteTimerConfig(timer.Name(), tm.date())
 	if err != nil {
-		dialog.ShowError(fmt.Errorf("删除配置失败: %v", err), fyne.CurrentApp().Driver().AllWindows()[0])
+		dialog.ShowError(fmt.Errorf("删除配置失败: %v", err), tm.window)
 		return
 	}
 
-	for i, t := range tm.timers {
+	// 先重置，运行中的工作时段记录为被打断，等记录保存后再关闭
+	timer.Reset()
+	timer.engine.Drain()
+	timer.close()
+	tm.mu.Lock()
+	key := dateKey(tm.currentDate)
+	for i, t := range tm.timers[key] {
 		if t == timer {
-			tm.timers = append(tm.timers[:i], tm.timers[i+1:]...)
+			tm.timers[key] = append(tm.timers[key][:i], tm.timers[key][i+1:]...)
 			break
 		}
 	}
+	tm.mu.Unlock()
 
 	tm.updateLayout()
-
-	dates, _ := tm.getAvailableDates()
-	tm.dateSelect.Options = dates
-	tm.dateSelect.Refresh()
 }
 
 func (tm *TimerManager) updateLayout() {
+	tm.layoutMu.Lock()
+	defer tm.layoutMu.Unlock()
+
 	if tm.container == nil {
 		return
 	}
@@ -248,7 +488,7 @@ func (tm *TimerManager) updateLayout() {
 
 	grid := container.NewGridWithColumns(2)
 
-	for _, timer := range tm.timers {
+	for _, timer := range tm.snapshot() {
 		if timer != nil && timer.container != nil {
 			grid.Add(timer.container)
 		}
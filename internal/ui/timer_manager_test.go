@@ -0,0 +1,267 @@
+package ui
+
+import (
+	"TodoList/internal/audio"
+	"TodoList/internal/config"
+	"TodoList/internal/models"
+	"TodoList/internal/storage"
+	"TodoList/internal/timer"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+)
+
+// newTestManager 创建使用临时数据库和手动时钟的 TimerManager
+func newTestManager(t *testing.T, notifier Notifier) (*TimerManager, *timer.ManualClock) {
+	t.Helper()
+	test.NewApp()
+
+	db, err := storage.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	defaults := config.DefaultConfig().Pomodoro
+	w := test.NewWindow(nil)
+	t.Cleanup(w.Close)
+	tm := NewTimerManager(db, defaults, audio.NewPlayer(&audio.NullSink{}), notifier, w)
+	w.SetContent(tm.container)
+	clock := timer.NewManualClock(time.Now())
+	tm.clock = clock
+	t.Cleanup(func() {
+		for _, timer := range tm.allTimers() {
+			timer.close()
+		}
+		flushUI()
+	})
+	return tm, clock
+}
+
+// addTestTimer 保存计时器配置并通过本地接口的查找方式加入界面
+func addTestTimer(t *testing.T, tm *TimerManager, name string, work, shortBreak time.Duration) *PomodoroTimer {
+	t.Helper()
+	err := tm.db.SaveTimerConfig(&models.TimerConfig{
+		Name:          name,
+		WorkDuration:  work,
+		BreakDuration: shortBreak,
+		LongBreak:     3 * shortBreak,
+		Date:          tm.date(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	timer, err := tm.timerByName(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return timer
+}
+
+// flushUI 等待 runOnUI 队列中已有的界面更新执行完
+func flushUI() {
+	done := make(chan struct{})
+	runOnUI(func() { close(done) })
+	<-done
+}
+
+func TestTimerManagerConcurrentControl(t *testing.T) {
+	tm, clock := newTestManager(t, nil)
+	a := addTestTimer(t, tm, "A", 25*time.Minute, 5*time.Minute)
+	b := addTestTimer(t, tm, "B", 50*time.Minute, 10*time.Minute)
+
+	const rounds = 50
+	var wg sync.WaitGroup
+	run := func(fn func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				fn(i)
+			}
+		}()
+	}
+
+	// 本地接口控制计时器
+	run(func(i int) {
+		tm.StartTimer("A")
+		tm.PauseTimer("B")
+		tm.ListTimers()
+	})
+	run(func(i int) {
+		tm.StartTimer("B")
+		tm.ResetTimer("A")
+	})
+	// 计时协程推进时间
+	run(func(i int) {
+		clock.Advance(time.Minute)
+	})
+	// 界面事件中执行的按钮回调
+	run(func(i int) {
+		a.startButton.OnTapped()
+		b.resetButton.OnTapped()
+		b.skipButton.OnTapped()
+	})
+	// 设置窗口修改计时器
+	run(func(i int) {
+		cfg := a.savedConfig()
+		cfg.WorkDuration = time.Duration(20+i%10) * time.Minute
+		a.setSavedConfig(cfg)
+		tm.saveTimer(a)
+	})
+	// 配置文件重新加载
+	run(func(i int) {
+		cfg := config.DefaultConfig().Pomodoro
+		cfg.LongBreakAfter = 2 + i%3
+		runOnUI(func() { tm.ApplyPomodoroConfig(cfg) })
+	})
+	// 托盘每秒刷新
+	run(func(i int) {
+		for _, timer := range tm.snapshot() {
+			trayLabel(timer)
+		}
+		runOnUI(tm.updateLayout)
+	})
+
+	wg.Wait()
+	flushUI()
+
+	if got := len(tm.snapshot()); got != 2 {
+		t.Fatalf("timers = %d, want 2", got)
+	}
+	for _, timer := range []*PomodoroTimer{a, b} {
+		if remaining := timer.GetRemainingTime(); remaining <= 0 || remaining > time.Hour {
+			t.Fatalf("%s remaining = %v", timer.Name(), remaining)
+		}
+	}
+}
+
+func TestTimerManagerRenameWhileListing(t *testing.T) {
+	tm, clock := newTestManager(t, nil)
+	a := addTestTimer(t, tm, "A", 25*time.Minute, 5*time.Minute)
+	a.Start()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			cfg := a.savedConfig()
+			cfg.Name = []string{"A", "A2"}[i%2]
+			a.setSavedConfig(cfg)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			tm.ListTimers()
+			clock.Advance(time.Second)
+		}
+	}()
+	wg.Wait()
+	flushUI()
+
+	if name := a.Name(); name != "A" && name != "A2" {
+		t.Fatalf("name = %q", name)
+	}
+}
+
+// onUI 像界面事件一样在界面协程中执行 fn，等它完成后返回
+func onUI(fn func()) {
+	runOnUI(fn)
+	flushUI()
+}
+
+// recordSaved 返回接收保存的番茄钟记录的通道
+func recordSaved(tm *TimerManager) <-chan *models.PomodoroRecord {
+	records := make(chan *models.PomodoroRecord, 10)
+	tm.SetOnRecordSaved(func(record *models.PomodoroRecord) { records <- record })
+	return records
+}
+
+func receiveRecord(t *testing.T, records <-chan *models.PomodoroRecord) *models.PomodoroRecord {
+	t.Helper()
+	select {
+	case record := <-records:
+		return record
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out waiting for a pomodoro record")
+	}
+	return nil
+}
+
+func TestTimerManagerKeepsTimersAcrossDates(t *testing.T) {
+	tm, clock := newTestManager(t, nil)
+	records := recordSaved(tm)
+	today := tm.date()
+	a := addTestTimer(t, tm, "A", 25*time.Minute, 5*time.Minute)
+	a.Start()
+
+	// 切换到其他日期时计时器只是隐藏，继续计时并写入完成的记录
+	onUI(func() { tm.onDateSelected(today.AddDate(0, 0, 1)) })
+	if got := len(tm.snapshot()); got != 0 {
+		t.Fatalf("timers on the next day = %d, want 0", got)
+	}
+	if !a.IsRunning() {
+		t.Fatal("timer stopped after switching dates")
+	}
+	clock.Advance(25 * time.Minute)
+	if record := receiveRecord(t, records); record.Interrupted {
+		t.Fatalf("record = %+v, want a completed work session", record)
+	}
+
+	// 切换回来时显示同一个计时器
+	onUI(func() { tm.onDateSelected(today) })
+	if timers := tm.snapshot(); len(timers) != 1 || timers[0] != a {
+		t.Fatalf("timers after switching back = %v, want the original timer", timers)
+	}
+	if found, err := tm.timerByName("A"); err != nil || found != a {
+		t.Fatalf("timerByName = %v, %v, want the original timer", found, err)
+	}
+}
+
+func TestRemoveTimerRecordsInterruption(t *testing.T) {
+	tm, clock := newTestManager(t, nil)
+	records := recordSaved(tm)
+	a := addTestTimer(t, tm, "A", 25*time.Minute, 5*time.Minute)
+	a.Start()
+	clock.Advance(10 * time.Minute)
+
+	// 删除运行中的计时器时记录被打断的工作时段
+	onUI(func() { tm.removeTimer(a) })
+	if record := receiveRecord(t, records); !record.Interrupted {
+		t.Fatalf("record = %+v, want an interrupted work session", record)
+	}
+	if got := len(tm.snapshot()); got != 0 {
+		t.Fatalf("timers = %d, want 0", got)
+	}
+}
+
+func TestFocusTaskDialogUsesMainWindow(t *testing.T) {
+	tm, _ := newTestManager(t, nil)
+
+	// 没有番茄钟时提示显示在传入的主窗口中，而不是任意一个窗口
+	other := test.NewWindow(nil)
+	defer other.Close()
+	tm.FocusTask(&models.Task{Title: "写周报"})
+
+	if tm.window.Canvas().Overlays().Top() == nil {
+		t.Fatal("no dialog shown in the main window")
+	}
+	if other.Canvas().Overlays().Top() != nil {
+		t.Fatal("dialog shown in another window")
+	}
+}
+
+func TestEmptyTrayLabel(t *testing.T) {
+	if got := emptyTrayLabel(time.Now()); got != "今天还没有番茄钟" {
+		t.Fatalf("today label = %q", got)
+	}
+	date := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+	if got := emptyTrayLabel(date); got != "2024-03-01 还没有番茄钟" {
+		t.Fatalf("label = %q", got)
+	}
+}
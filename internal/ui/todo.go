@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"TodoList/internal/config"
 	"TodoList/internal/models"
 	"fmt"
 	"fyne.io/fyne/v2"
@@ -8,22 +9,18 @@ import (
 	"fyne.io/fyne/v2/theme"
 	"image/color"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"TodoList/internal/storage"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 )
 
-type TaskStatus string
-
-const (
-	StatusTodo  TaskStatus = "TODO"
-	StatusDoing TaskStatus = "DOING"
-	StatusDone  TaskStatus = "DONE"
-	StatusUndo  TaskStatus = "UNDO"
-)
+const tomatoIconPath = "assets/icons/tomato.png"
 
 // TodoItem 表示单个待办事项
 type TodoItem struct {
@@ -44,27 +41,26 @@ func NewTodoItem(task *models.Task, parent *TodoList) *TodoItem {
 
 	// 创建勾选按钮
 	checkBtn := widget.NewButtonWithIcon("", theme.ConfirmIcon(), func() {
-		currentStatus := TaskStatus(item.task.Status)
-		switch currentStatus {
-		case StatusTodo:
-			item.parent.moveTask(item.task, StatusDoing)
-		case StatusDoing:
-			item.parent.moveTask(item.task, StatusDone)
-		case StatusUndo:
-			item.parent.moveTask(item.task, StatusTodo)
+		switch item.task.Status {
+		case models.StatusTodo:
+			item.parent.moveTask(item.task, models.StatusDoing)
+		case models.StatusDoing:
+			item.parent.moveTask(item.task, models.StatusDone)
+		case models.StatusCancelled:
+			item.parent.moveTask(item.task, models.StatusTodo)
 		}
 	})
 	checkBtn.Resize(fyne.NewSize(10, 10))
 
-	title := canvas.NewText(task.Title, color.Black)
+	title := canvas.NewText(task.Title, theme.Color(theme.ColorNameForeground))
 	title.TextStyle = fyne.TextStyle{Italic: true}
 	title.Alignment = fyne.TextAlignCenter
 	title.Resize(fyne.NewSize(200, 0))
 
 	// 创建删除按钮，根据状态设置不同的行为
 	deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
-		if TaskStatus(item.task.Status) == StatusUndo {
-			// 如果是 Undo 状态，直接删除任务
+		if item.task.Status == models.StatusCancelled {
+			// 已取消的任务直接删除
 			if err := parent.db.DeleteTask(item.task.ID); err != nil {
 				fmt.Println("Error deleting task:", err)
 				return
@@ -72,68 +68,215 @@ func NewTodoItem(task *models.Task, parent *TodoList) *TodoItem {
 			// 从内存中移除任务
 			parent.removeTask(item.task)
 		} else {
-			// 其他状态移动到 Undo
-			parent.moveTask(item.task, StatusUndo)
+			// 其他状态先标记为取消
+			parent.moveTask(item.task, models.StatusCancelled)
 		}
 	})
 
 	// 创建编辑按钮
 	editBtn := widget.NewButtonWithIcon("", theme.DocumentCreateIcon(), item.onEditClicked)
 
+	// 创建专注按钮，将当前番茄钟关联到该任务
+	focusBtn := widget.NewButtonWithIcon("", theme.MediaRecordIcon(), func() {
+		if parent.onFocusTask != nil {
+			parent.onFocusTask(item.task)
+		}
+	})
+
 	// 创建按钮容器
 	buttons := container.NewHBox(checkBtn, deleteBtn)
 
-	item.container = container.NewHBox(
+	row := container.NewHBox(
 		container.NewHBox(buttons),
-		title,
-		layout.NewSpacer(),
-		editBtn,
+		newPriorityBadge(task.Priority),
 	)
+	if task.RecurrenceID != nil {
+		row.Add(widget.NewIcon(theme.ViewRefreshIcon()))
+	}
+	row.Add(title)
+	for _, tag := range task.Tags {
+		row.Add(newTagChip(tag))
+	}
+	if task.RolloverCount > 0 {
+		row.Add(newRolloverChip(task))
+	}
+	row.Add(layout.NewSpacer())
+
+	if task.DueAt != nil {
+		row.Add(newDueLabel(task))
+	}
+
+	// 显示该任务已完成的番茄钟数量
+	if count := parent.pomodoroCount(task.ID); count > 0 {
+		icon := canvas.NewImageFromFile(tomatoIconPath)
+		icon.FillMode = canvas.ImageFillContain
+		icon.SetMinSize(fyne.NewSize(16, 16))
+		row.Add(container.NewHBox(icon, widget.NewLabel(fmt.Sprintf("%d", count))))
+	}
+
+	// 有子任务时显示完成进度，点击展开子任务
+	subtasks := parent.subtasks(task.ID)
+	expanded := len(subtasks) > 0 && parent.isExpanded(task.ID)
+	if len(subtasks) > 0 {
+		done, total := models.SubtaskProgress(subtasks)
+		icon := theme.MenuExpandIcon()
+		if expanded {
+			icon = theme.MenuDropDownIcon()
+		}
+		row.Add(widget.NewButtonWithIcon(fmt.Sprintf("%d/%d", done, total), icon, func() {
+			parent.toggleExpanded(item.task.ID)
+		}))
+	} else if task.ParentID == nil {
+		row.Add(widget.NewButtonWithIcon("", theme.ContentAddIcon(), item.onAddSubtaskClicked))
+	}
+
+	row.Add(focusBtn)
+	row.Add(editBtn)
 	item.label = title
+
+	// 拖动任务到其他列修改状态，在同一列中拖动修改顺序
+	item.container = container.NewVBox(newDraggable(row, task.Title, func(pos fyne.Position) {
+		parent.onTaskDropped(item.task, pos)
+	}))
+	if expanded {
+		for _, sub := range subtasks {
+			item.container.Add(item.newSubtaskRow(sub))
+		}
+		item.container.Add(item.newAddSubtaskRow())
+	}
 	return item
 }
 
+// newAddSubtaskRow 创建展开的子任务下面的添加按钮
+func (i *TodoItem) newAddSubtaskRow() fyne.CanvasObject {
+	addBtn := widget.NewButtonWithIcon("添加子任务", theme.ContentAddIcon(), i.onAddSubtaskClicked)
+	addBtn.Importance = widget.LowImportance
+	return indent(container.NewHBox(addBtn))
+}
+
+// newSubtaskRow 创建子任务行，勾选后将子任务标记为完成
+func (i *TodoItem) newSubtaskRow(sub *models.Task) fyne.CanvasObject {
+	check := widget.NewCheck(sub.Title, nil)
+	check.Checked = sub.Status == models.StatusDone
+	check.OnChanged = func(checked bool) {
+		status := models.StatusTodo
+		if checked {
+			status = models.StatusDone
+		}
+		if sub.Status != status {
+			i.parent.moveTask(sub, status)
+		}
+	}
+	if sub.Status == models.StatusCancelled {
+		check.Disable()
+	}
+
+	deleteBtn := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+		if err := i.parent.db.DeleteTask(sub.ID); err != nil {
+			fmt.Println("Error deleting task:", err)
+			return
+		}
+		i.parent.removeTask(sub)
+	})
+	return indent(container.NewBorder(nil, nil, nil, deleteBtn, check))
+}
+
+// 处理添加子任务按钮点击
+func (i *TodoItem) onAddSubtaskClicked() {
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder("子任务标题")
+	items := []*widget.FormItem{widget.NewFormItem("标题", entry)}
+
+	dialog.ShowForm("添加子任务", "添加", "取消", items, func(ok bool) {
+		title := strings.TrimSpace(entry.Text)
+		if !ok || title == "" {
+			return
+		}
+		if err := i.parent.createSubtask(i.task, title); err != nil {
+			dialog.ShowError(fmt.Errorf("添加子任务失败: %v", err), i.parent.window)
+		}
+	}, i.parent.window)
+}
+
+// indent 将子任务相关的内容向右缩进
+func indent(obj fyne.CanvasObject) fyne.CanvasObject {
+	return container.New(layout.NewCustomPaddedLayout(0, 0, theme.IconInlineSize()*2, 0), obj)
+}
+
 // 处理编辑按钮点击
 func (i *TodoItem) onEditClicked() {
-	dialog := widget.NewEntry()
-	dialog.MultiLine = true
-	dialog.SetText(i.task.Title)
-
-	w := fyne.CurrentApp().NewWindow("编辑任务")
-	w.SetContent(container.NewVBox(
-		dialog,
-		container.NewHBox(
-			widget.NewButton("取消", func() {
-				w.Close()
-			}),
-			widget.NewButton("保存", func() {
-				i.updateText(dialog.Text)
-				w.Close()
-			}),
-		),
+	// 修改重复规则会影响以后的任务，保存后重新加载
+	showTaskEditor(i.task, i.parent, func() {
+		if err := i.parent.reload(); err != nil {
+			fmt.Println("Error loading tasks:", err)
+		}
+	})
+}
+
+// newPriorityBadge 创建显示优先级的彩色标记
+func newPriorityBadge(priority int) fyne.CanvasObject {
+	name := colorNamePriorityLow
+	switch {
+	case priority >= models.PriorityHigh:
+		name = colorNamePriorityHigh
+	case priority == models.PriorityMedium:
+		name = colorNamePriorityMedium
+	}
+
+	return newChip(models.PriorityName(priority), theme.Color(name), true)
+}
+
+// newTagChip 创建显示标签的彩色标记
+func newTagChip(tag string) fyne.CanvasObject {
+	return newChip("#"+tag, tagColor(tag), false)
+}
+
+// newChip 创建圆角背景上的小号白色文字
+func newChip(label string, fill color.Color, bold bool) fyne.CanvasObject {
+	background := canvas.NewRectangle(fill)
+	background.CornerRadius = 4
+	text := canvas.NewText(label, color.White)
+	text.TextSize = theme.CaptionTextSize()
+	text.TextStyle = fyne.TextStyle{Bold: bold}
+	return container.NewCenter(container.NewStack(
+		background,
+		container.New(layout.NewCustomPaddedLayout(1, 1, 4, 4), text),
 	))
-	w.Resize(fyne.NewSize(300, 200))
-	w.CenterOnScreen()
-	w.Show()
 }
 
-// 更新文本
-func (i *TodoItem) updateText(newText string) {
-	i.task.Title = newText
-	i.label.Text = newText
-	i.parent.refreshAllLists()
+// newRolloverChip 创建显示推迟次数的标记
+func newRolloverChip(task *models.Task) fyne.CanvasObject {
+	return newChip(fmt.Sprintf("推迟 %d 次", task.RolloverCount), theme.Color(theme.ColorNameWarning), false)
+}
+
+// newDueLabel 创建截止时间文本，已过期的任务使用错误颜色
+func newDueLabel(task *models.Task) fyne.CanvasObject {
+	now := time.Now()
+	format := "01-02 15:04"
+	if task.DueAt.Year() != now.Year() {
+		format = dueLayout
+	}
+
+	text := canvas.NewText(task.DueAt.Local().Format(format), theme.Color(theme.ColorNamePlaceHolder))
+	text.TextSize = theme.CaptionTextSize()
+	if task.IsOverdue(now) {
+		text.Color = theme.Color(theme.ColorNameError)
+		text.TextStyle = fyne.TextStyle{Bold: true}
+	}
+	return container.NewCenter(text)
 }
 
 // TodoList 表示一个状态的任务列表
 type StatusList struct {
-	status     TaskStatus
+	status     models.TaskStatus
 	items      []*TodoItem
+	heights    []float32 // 当前显示的每行高度，由 updateItemHeights 设置
 	list       *widget.List
 	parent     *TodoList
 	countLabel *widget.Label
 }
 
-func NewStatusList(status TaskStatus, parent *TodoList) *StatusList {
+func NewStatusList(status models.TaskStatus, parent *TodoList) *StatusList {
 	sl := &StatusList{
 		status: status,
 		parent: parent,
@@ -170,70 +313,107 @@ func (sl *StatusList) updateItems(tasks []*models.Task) {
 	sl.list.Refresh()
 }
 
+// 列内任务的排序方式
+const (
+	sortByPriority = "优先级"
+	sortByDue      = "截止时间"
+	sortByManual   = "手动排序"
+)
+
+// indexAt 返回绝对位置 pos 在列表中对应的插入位置
+func (sl *StatusList) indexAt(pos fyne.Position) int {
+	y := pos.Y - fyne.CurrentApp().Driver().AbsolutePositionForObject(sl.list).Y + sl.list.GetScrollOffset()
+	sl.parent.refreshMu.Lock()
+	heights := sl.heights
+	sl.parent.refreshMu.Unlock()
+	for i, height := range heights {
+		if y < height/2 {
+			return i
+		}
+		y -= height + theme.Padding()
+	}
+	return len(heights)
+}
+
 // 修改 TodoList 结构
 type TodoList struct {
-	tasks       map[string][]*models.Task
-	currentDate string
-	dateSelect  *widget.Select
-	todoList    *StatusList
-	doingList   *StatusList
-	doneList    *StatusList
-	undoList    *StatusList
-	input       *widget.Entry
-	addBtn      *widget.Button
-	container   *fyne.Container
-	db          *storage.Database // 添加数据库引用
-}
-
-func NewTodoList(db *storage.Database) *TodoList {
+	tasks         map[string][]*models.Task
+	currentDate   string
+	datePicker    *DatePicker
+	todoList      *StatusList
+	doingList     *StatusList
+	doneList      *StatusList
+	cancelledList *StatusList
+	input         *widget.Entry
+	addBtn        *widget.Button
+	container     *fyne.Container
+	db            *storage.Database // 添加数据库引用
+
+	pomodoroCounts map[int64]int      // 当前日期各任务完成的番茄钟数量
+	onFocusTask    func(*models.Task) // 选择专注任务的回调
+	sortBy         string             // 列内任务的排序方式
+	expanded       map[int64]bool     // 展开显示子任务的任务
+	tagFilter      map[string]bool    // 筛选的标签，键为小写名称，为空时显示全部任务
+	filterBar      *fyne.Container    // 标签筛选栏
+	window         fyne.Window        // 显示对话框的窗口
+	sortSelect     *widget.Select     // 排序方式选择器
+	selected       int64              // 键盘操作的任务，在列表中选中任务时设置
+	rolloverMode   string             // 转移未完成任务的方式: move 或 copy
+
+	// 计时协程和本地接口也会触发刷新，tasks、currentDate、pomodoroCounts 和 rolloverMode 需要加锁访问
+	mu sync.RWMutex
+	// 界面事件和 runOnUI 都会重建列表和筛选栏，同一时间只允许一个刷新
+	refreshMu sync.Mutex
+
+	columnBackgrounds map[*canvas.Rectangle]fyne.ThemeColorName // 各列的背景及对应的主题颜色
+}
+
+func NewTodoList(db *storage.Database, cfg config.TasksConfig) *TodoList {
 	todo := &TodoList{
-		tasks: make(map[string][]*models.Task),
-		input: widget.NewEntry(),
-		db:    db,
-	}
+		tasks:          make(map[string][]*models.Task),
+		input:          widget.NewEntry(),
+		db:             db,
+		pomodoroCounts: make(map[int64]int),
+		sortBy:         sortByPriority,
+		expanded:       make(map[int64]bool),
+		tagFilter:      make(map[string]bool),
+		rolloverMode:   cfg.RolloverMode,
 
-	// 从数据库加载日期列表
-	dates, err := db.GetDistinctDates()
-	if err != nil {
-		dates = []string{}
+		columnBackgrounds: make(map[*canvas.Rectangle]fyne.ThemeColorName),
 	}
 
-	// 确保今天的日期在列表中
+	// 启动时将以前未完成的任务转到今天
 	today := time.Now().Format("2006-01-02")
-	hasToday := false
-	for _, date := range dates {
-		if date == today {
-			hasToday = true
-			break
+	if cfg.AutoRollover {
+		if _, err := db.RolloverTasks(today, cfg.RolloverMode == config.RolloverCopy); err != nil {
+			fmt.Println("Error rolling over tasks:", err)
 		}
 	}
-	if !hasToday {
-		dates = append(dates, today)
-	}
-	sort.Strings(dates)
 
-	// 初始化日期选择器
+	// 初始化日期选择器，默认选中今天
 	todo.currentDate = today
-	todo.dateSelect = widget.NewSelect(dates, todo.onDateSelected)
+	todo.datePicker = NewDatePicker(db.GetActiveDates, func(date time.Time) {
+		todo.onDateSelected(date.Format("2006-01-02"))
+	})
 
 	// 先初始化所有列表
 	todo.todoList = &StatusList{
-		status: StatusTodo,
+		status: models.StatusTodo,
 		parent: todo,
 		items:  make([]*TodoItem, 0),
 	}
 	todo.doingList = &StatusList{
-		status: StatusDoing,
+		status: models.StatusDoing,
 		parent: todo,
 		items:  make([]*TodoItem, 0),
 	}
 	todo.doneList = &StatusList{
-		status: StatusDone,
+		status: models.StatusDone,
 		parent: todo,
 		items:  make([]*TodoItem, 0),
 	}
-	todo.undoList = &StatusList{
-		status: StatusUndo,
+	todo.cancelledList = &StatusList{
+		status: models.StatusCancelled,
 		parent: todo,
 		items:  make([]*TodoItem, 0),
 	}
@@ -245,96 +425,423 @@ func NewTodoList(db *storage.Database) *TodoList {
 	if err := todo.loadTasksForDate(today); err != nil {
 		fmt.Println("Error loading tasks:", err)
 	}
-	todo.dateSelect.SetSelected(today) // 设置默认选中今天
 
 	return todo
 }
 
-// 加载指定日期的任务
+// 加载指定日期的任务，先生成重复任务在这一天的实例
 func (t *TodoList) loadTasksForDate(date string) error {
+	if err := t.db.MaterializeRecurrences(date); err != nil {
+		return err
+	}
+
 	tasks, err := t.db.GetTasksByDate(date)
 	if err != nil {
 		return err
 	}
 
+	counts, err := t.db.GetPomodoroCountsByDate(date)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
 	t.tasks[date] = tasks
+	t.pomodoroCounts = counts
+	t.mu.Unlock()
+
 	t.refreshAllLists()
 	return nil
 }
 
+// reload 重新加载当前日期的任务
+func (t *TodoList) reload() error {
+	return t.loadTasksForDate(t.date())
+}
+
+// refreshPomodoroCounts 重新加载当前日期各任务的番茄钟数量
+func (t *TodoList) refreshPomodoroCounts() {
+	counts, err := t.db.GetPomodoroCountsByDate(t.date())
+	if err != nil {
+		fmt.Println("Error loading pomodoro counts:", err)
+		return
+	}
+
+	t.mu.Lock()
+	t.pomodoroCounts = counts
+	t.mu.Unlock()
+	t.refreshAllLists()
+}
+
+// date 返回当前选择的日期
+func (t *TodoList) date() string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.currentDate
+}
+
+// pomodoroCount 返回任务在当前日期完成的番茄钟数量
+func (t *TodoList) pomodoroCount(taskID int64) int {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.pomodoroCounts[taskID]
+}
+
+// SetOnFocusTask 设置选择专注任务的回调
+func (t *TodoList) SetOnFocusTask(callback func(*models.Task)) {
+	t.onFocusTask = callback
+}
+
 // 日期选择回调
 func (t *TodoList) onDateSelected(date string) {
 	if date == "" {
 		return
 	}
+	t.mu.Lock()
 	t.currentDate = date
+	t.mu.Unlock()
 	if err := t.loadTasksForDate(date); err != nil {
 		fmt.Println("Error loading tasks:", err)
 	}
 }
 
+// setRolloverMode 设置转移未完成任务的方式，配置重新加载时调用
+func (t *TodoList) setRolloverMode(mode string) {
+	t.mu.Lock()
+	t.rolloverMode = mode
+	t.mu.Unlock()
+}
+
+// rolloverToToday 将以前未完成的任务转到今天并切换到今天
+func (t *TodoList) rolloverToToday() {
+	t.mu.RLock()
+	asCopy := t.rolloverMode == config.RolloverCopy
+	t.mu.RUnlock()
+
+	today := time.Now().Format("2006-01-02")
+	count, err := t.db.RolloverTasks(today, asCopy)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("转移未完成的任务失败: %v", err), t.window)
+		return
+	}
+
+	if t.date() == today {
+		if err := t.reload(); err != nil {
+			fmt.Println("Error loading tasks:", err)
+		}
+	} else {
+		t.datePicker.Today()
+	}
+	dialog.ShowInformation("转入未完成", fmt.Sprintf("已将 %d 个未完成的任务转到今天", count), t.window)
+}
+
+// subtasks 返回当前日期中 parentID 的子任务，按创建顺序排列
+func (t *TodoList) subtasks(parentID int64) []*models.Task {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var result []*models.Task
+	for _, task := range t.tasks[t.currentDate] {
+		if task.ParentID != nil && *task.ParentID == parentID {
+			result = append(result, task)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// isExpanded 返回任务的子任务是否展开
+func (t *TodoList) isExpanded(taskID int64) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.expanded[taskID]
+}
+
+// toggleExpanded 展开或收起任务的子任务
+func (t *TodoList) toggleExpanded(taskID int64) {
+	t.mu.Lock()
+	t.expanded[taskID] = !t.expanded[taskID]
+	t.mu.Unlock()
+	t.refreshAllLists()
+}
+
+// 排序方式选择回调
+func (t *TodoList) onSortSelected(sortBy string) {
+	t.mu.Lock()
+	t.sortBy = sortBy
+	t.mu.Unlock()
+	t.refreshAllLists()
+}
+
 // 修改添加任务的方法
 func (t *TodoList) addTask() {
 	if text := t.input.Text; text != "" {
-		task := &models.Task{
-			Title:     text,
-			Status:    models.TaskStatus(string(StatusTodo)),
-			CreatedAt: time.Now(),
-			Date:      t.currentDate,
-			Priority:  1, // 设置默认优先级
-		}
-
-		// 保存到数据库
-		if err := t.db.SaveTask(task); err != nil {
+		if err := t.createTask(text, t.date()); err != nil {
 			// 处理错误，可以显示一个对话框
 			fmt.Println("Error saving task:", err)
 			return
 		}
 
-		// 更新内存中的任务列表
-		if _, ok := t.tasks[t.currentDate]; !ok {
-			t.tasks[t.currentDate] = make([]*models.Task, 0)
-		}
-		t.tasks[t.currentDate] = append(t.tasks[t.currentDate], task)
-
-		// 清空输入框并刷新列表
+		// 清空输入框
 		t.input.SetText("")
+	}
+}
+
+// createTask 保存新的待办任务，text 中的 #标签 会被识别，date 为当前选择的日期时同时刷新列表
+func (t *TodoList) createTask(text, date string) error {
+	title, tags := models.ParseTags(text)
+	if title == "" {
+		return fmt.Errorf("任务标题不能为空")
+	}
+
+	task := &models.Task{
+		Title:     title,
+		Status:    models.StatusTodo,
+		CreatedAt: time.Now(),
+		Date:      date,
+		Priority:  1, // 设置默认优先级
+		Tags:      tags,
+	}
+
+	// 保存到数据库
+	if err := t.db.SaveTask(task); err != nil {
+		return err
+	}
+
+	// 更新内存中的任务列表
+	t.mu.Lock()
+	t.tasks[task.Date] = append(t.tasks[task.Date], task)
+	current := task.Date == t.currentDate
+	t.mu.Unlock()
+
+	if current {
 		t.refreshAllLists()
 	}
+	return nil
+}
+
+// createSubtask 为 parent 添加子任务并展开显示
+func (t *TodoList) createSubtask(parent *models.Task, title string) error {
+	task := &models.Task{
+		Title:     title,
+		Status:    models.StatusTodo,
+		CreatedAt: time.Now(),
+		Date:      parent.Date,
+		Priority:  parent.Priority,
+		ParentID:  &parent.ID,
+	}
+	if err := t.db.SaveTask(task); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	t.tasks[task.Date] = append(t.tasks[task.Date], task)
+	t.expanded[parent.ID] = true
+	t.mu.Unlock()
+
+	t.refreshAllLists()
+	return nil
 }
 
-// 修改移动任务的方法
-func (t *TodoList) moveTask(task *models.Task, newStatus TaskStatus) {
-	task.Status = models.TaskStatus(string(newStatus))
-	if newStatus == StatusDone {
-		now := time.Now()
-		task.CompletedAt = &now
+// moveTask 修改任务状态并保存，不允许的变更会被忽略
+func (t *TodoList) moveTask(task *models.Task, newStatus models.TaskStatus) {
+	previous := *task
+	if err := task.SetStatus(newStatus, time.Now()); err != nil {
+		fmt.Println("Error updating task:", err)
+		return
 	}
 
 	// 更新数据库
 	if err := t.db.SaveTask(task); err != nil {
-		// 处理错误，可以显示一个对话框
 		fmt.Println("Error updating task:", err)
+		*task = previous
 		return
 	}
 
+	// 子任务全部完成后父任务可能被自动完成，需要重新加载
+	if task.ParentID != nil {
+		if err := t.reload(); err != nil {
+			fmt.Println("Error loading tasks:", err)
+		}
+		return
+	}
 	t.refreshAllLists()
 }
 
+// onTaskDropped 将拖动的任务放到松开鼠标时所在的列和位置
+func (t *TodoList) onTaskDropped(task *models.Task, pos fyne.Position) {
+	for _, sl := range t.statusLists() {
+		if !containsPoint(sl.list, pos) {
+			continue
+		}
+		if err := t.placeTask(task, sl.status, sl.indexAt(pos)); err != nil {
+			dialog.ShowError(fmt.Errorf("移动任务失败: %v", err), t.window)
+		}
+		return
+	}
+}
+
+// placeTask 将任务移动到 status 列中第 index 个显示的任务前面，并保存这一列的手动顺序。
+// 在同一列中移动时切换到手动排序
+func (t *TodoList) placeTask(task *models.Task, status models.TaskStatus, index int) error {
+	// 插入到这个任务前面，为 nil 时放到最后
+	var before *models.Task
+	visible := t.getTasksByStatus(status)
+	for _, other := range visible[min(index, len(visible)):] {
+		if other.ID != task.ID {
+			before = other
+			break
+		}
+	}
+
+	sameColumn := task.Status == status
+	if !sameColumn {
+		previous := *task
+		if err := task.SetStatus(status, time.Now()); err != nil {
+			return err
+		}
+		if err := t.db.SaveTask(task); err != nil {
+			*task = previous
+			return err
+		}
+	}
+
+	// 按保存的手动顺序插入到 before 前面，按优先级或截止时间显示时其他任务的手动顺序不变。
+	// 被标签筛选隐藏的任务也重新编号，保持它们之间的顺序
+	t.mu.Lock()
+	var column []*models.Task
+	for _, other := range t.tasks[t.currentDate] {
+		if other.Status == status && other.ParentID == nil && other.ID != task.ID {
+			column = append(column, other)
+		}
+	}
+	sortTasks(column, sortByManual)
+	at := len(column)
+	for i, other := range column {
+		if other == before {
+			at = i
+			break
+		}
+	}
+	column = append(column[:at], append([]*models.Task{task}, column[at:]...)...)
+	ids := make([]int64, len(column))
+	for i, other := range column {
+		other.Position = i + 1
+		ids[i] = other.ID
+	}
+	t.mu.Unlock()
+
+	if err := t.db.SetTaskPositions(ids); err != nil {
+		return err
+	}
+
+	if sameColumn && t.sortBy != sortByManual {
+		t.sortSelect.SetSelected(sortByManual)
+	} else {
+		t.refreshAllLists()
+	}
+	t.selectTask(task)
+	return nil
+}
+
+// selectedTask 返回列表中选中的任务，没有选中或已不在当前日期时返回 nil
+func (t *TodoList) selectedTask() *models.Task {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	for _, task := range t.tasks[t.currentDate] {
+		if task.ID == t.selected && task.ParentID == nil {
+			return task
+		}
+	}
+	return nil
+}
+
+// selectTask 在任务所在的列表中选中任务
+func (t *TodoList) selectTask(task *models.Task) {
+	for _, sl := range t.statusLists() {
+		if sl.status != task.Status {
+			continue
+		}
+		for i, other := range t.getTasksByStatus(sl.status) {
+			if other.ID == task.ID {
+				sl.list.UnselectAll()
+				sl.list.Select(i)
+				sl.list.ScrollTo(i)
+			}
+		}
+	}
+}
+
+// moveSelected 将选中的任务移动到左边（offset 为 -1）或右边（offset 为 1）一列的最后
+func (t *TodoList) moveSelected(offset int) {
+	task := t.selectedTask()
+	if task == nil {
+		return
+	}
+	i := 0
+	for i < len(models.TaskStatuses) && models.TaskStatuses[i] != task.Status {
+		i++
+	}
+	if i+offset < 0 || i+offset >= len(models.TaskStatuses) {
+		return
+	}
+
+	status := models.TaskStatuses[i+offset]
+	if err := t.placeTask(task, status, len(t.getTasksByStatus(status))); err != nil {
+		dialog.ShowError(fmt.Errorf("移动任务失败: %v", err), t.window)
+	}
+}
+
+// reorderSelected 将选中的任务在列内上移（offset 为 -1）或下移（offset 为 1）一位
+func (t *TodoList) reorderSelected(offset int) {
+	task := t.selectedTask()
+	if task == nil {
+		return
+	}
+	tasks := t.getTasksByStatus(task.Status)
+	for i, other := range tasks {
+		if other.ID != task.ID {
+			continue
+		}
+		if i+offset < 0 || i+offset >= len(tasks) {
+			return
+		}
+		// 下移时插入到后一个任务的后面
+		index := i + offset
+		if offset > 0 {
+			index++
+		}
+		if err := t.placeTask(task, task.Status, index); err != nil {
+			dialog.ShowError(fmt.Errorf("移动任务失败: %v", err), t.window)
+		}
+		return
+	}
+}
+
 // setup 方法中的列表布局
 func (t *TodoList) setup() {
 	// 创建标题
 	title := widget.NewLabelWithStyle("任务管理器", fyne.TextAlignCenter, fyne.TextStyle{Bold: true})
 
+	// 创建排序选择器
+	// 先选中当前的排序方式再设置回调，此时列表和筛选栏还没有创建
+	t.sortSelect = widget.NewSelect([]string{sortByPriority, sortByDue, sortByManual}, nil)
+	t.sortSelect.SetSelected(t.sortBy)
+	t.sortSelect.OnChanged = t.onSortSelected
+
 	// 创建日期选择器
 	dateContainer := container.NewHBox(
 		widget.NewLabel("Date:"),
-		t.dateSelect,
+		t.datePicker.container,
+		widget.NewButtonWithIcon("转入未完成", theme.MailForwardIcon(), t.rolloverToToday),
+		layout.NewSpacer(),
+		widget.NewLabel("排序:"),
+		t.sortSelect,
 	)
 
 	// 创建输入框和添加按钮
 	t.input = widget.NewEntry()
-	t.input.SetPlaceHolder("Add a new task...")
+	t.input.SetPlaceHolder("Add a new task... 可以使用 #标签")
 	t.addBtn = widget.NewButtonWithIcon("Add Task", theme.ContentAddIcon(), t.addTask)
 
 	inputContainer := container.NewBorder(
@@ -343,24 +850,28 @@ func (t *TodoList) setup() {
 	)
 
 	// 创建四列布局
-	todoList, todoColumn, todoCount := createColumnList("Todo", color.NRGBA{R: 240, G: 248, B: 255, A: 255}, t, StatusTodo)
-	doingList, doingColumn, doingCount := createColumnList("Doing", color.NRGBA{R: 255, G: 250, B: 240, A: 255}, t, StatusDoing)
-	doneList, doneColumn, doneCount := createColumnList("Done", color.NRGBA{R: 240, G: 255, B: 240, A: 255}, t, StatusDone)
-	undoList, undoColumn, undoCount := createColumnList("Undo", color.NRGBA{R: 255, G: 240, B: 240, A: 255}, t, StatusUndo)
+	todoList, todoColumn, todoCount := createColumnList("Todo", colorNameTodoColumn, t, models.StatusTodo)
+	doingList, doingColumn, doingCount := createColumnList("Doing", colorNameDoingColumn, t, models.StatusDoing)
+	doneList, doneColumn, doneCount := createColumnList("Done", colorNameDoneColumn, t, models.StatusDone)
+	cancelledList, cancelledColumn, cancelledCount := createColumnList("Cancelled", colorNameCancelledColumn, t, models.StatusCancelled)
 
 	listsContainer := container.NewGridWithColumns(4,
 		todoColumn,
 		doingColumn,
 		doneColumn,
-		undoColumn,
+		cancelledColumn,
 	)
 
+	// 标签筛选栏，加载任务后由 refreshTagFilter 填充
+	t.filterBar = container.NewHBox()
+
 	// 使用 Border 布局组织整体界面
 	t.container = container.NewBorder(
 		container.NewVBox(
 			title,
 			dateContainer, // 添加日期选择器
 			inputContainer,
+			container.NewHScroll(t.filterBar),
 		),
 		nil, nil, nil,
 		listsContainer,
@@ -376,12 +887,12 @@ func (t *TodoList) setup() {
 	t.doneList.list = doneList
 	t.doneList.countLabel = doneCount
 
-	t.undoList.list = undoList
-	t.undoList.countLabel = undoCount
+	t.cancelledList.list = cancelledList
+	t.cancelledList.countLabel = cancelledCount
 }
 
 // 创建列表列
-func createColumnList(title string, bgColor color.Color, t *TodoList, status TaskStatus) (*widget.List, *fyne.Container, *widget.Label) {
+func createColumnList(title string, bgColor fyne.ThemeColorName, t *TodoList, status models.TaskStatus) (*widget.List, *fyne.Container, *widget.Label) {
 	countLabel := widget.NewLabel("0")
 
 	list := widget.NewList(
@@ -403,6 +914,20 @@ func createColumnList(title string, bgColor color.Color, t *TodoList, status Tas
 		},
 	)
 
+	// 选中的任务可以使用快捷键移动，同一时间只有一列有选中的任务
+	list.OnSelected = func(id widget.ListItemID) {
+		if tasks := t.getTasksByStatus(status); id < len(tasks) {
+			t.mu.Lock()
+			t.selected = tasks[id].ID
+			t.mu.Unlock()
+		}
+		for _, sl := range t.statusLists() {
+			if sl.status != status {
+				sl.list.UnselectAll()
+			}
+		}
+	}
+
 	// 创建标题和数量显示
 	titleLabel := widget.NewLabelWithStyle(title, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
 	header := container.NewHBox(
@@ -411,8 +936,9 @@ func createColumnList(title string, bgColor color.Color, t *TodoList, status Tas
 		countLabel,
 	)
 
-	// 创建带背景色的容器
-	background := canvas.NewRectangle(bgColor)
+	// 创建带背景色的容器，主题切换时由 applyTheme 更新颜色
+	background := canvas.NewRectangle(theme.Color(bgColor))
+	t.columnBackgrounds[background] = bgColor
 
 	// 返回���个值
 	return list, container.NewBorder(
@@ -424,43 +950,205 @@ func createColumnList(title string, bgColor color.Color, t *TodoList, status Tas
 }
 
 // 获取指定状态的任务
-func (t *TodoList) getTasksByStatus(status TaskStatus) []*models.Task {
+func (t *TodoList) getTasksByStatus(status models.TaskStatus) []*models.Task {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
 	var result []*models.Task
 	if tasks, ok := t.tasks[t.currentDate]; ok {
 		for _, task := range tasks {
-			if models.TaskStatus(string(status)) == task.Status {
+			// 子任务显示在父任务下面
+			if status == task.Status && task.ParentID == nil && t.matchesFilter(task) {
 				result = append(result, task)
 			}
 		}
 	}
+	sortTasks(result, t.sortBy)
 	return result
 }
 
+// matchesFilter 判断任务是否带有任意一个筛选的标签，调用时必须持有锁
+func (t *TodoList) matchesFilter(task *models.Task) bool {
+	if len(t.tagFilter) == 0 {
+		return true
+	}
+	for _, tag := range task.Tags {
+		if t.tagFilter[strings.ToLower(tag)] {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshTagFilter 根据当前日期任务的标签重建筛选栏，移除已不存在的筛选标签，调用时必须持有 refreshMu
+func (t *TodoList) refreshTagFilter() {
+	t.mu.Lock()
+	var tags []string
+	present := make(map[string]bool)
+	for _, task := range t.tasks[t.currentDate] {
+		for _, tag := range task.Tags {
+			if key := strings.ToLower(tag); !present[key] {
+				present[key] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	for key := range t.tagFilter {
+		if !present[key] {
+			delete(t.tagFilter, key)
+		}
+	}
+	filtering := len(t.tagFilter) > 0
+	selected := make(map[string]bool, len(t.tagFilter))
+	for key := range t.tagFilter {
+		selected[key] = true
+	}
+	t.mu.Unlock()
+
+	sort.Slice(tags, func(i, j int) bool { return strings.ToLower(tags[i]) < strings.ToLower(tags[j]) })
+
+	t.filterBar.RemoveAll()
+	if len(tags) == 0 {
+		return
+	}
+
+	t.filterBar.Add(widget.NewLabel("标签:"))
+	all := widget.NewButton("全部", func() { t.setTagFilter("") })
+	if !filtering {
+		all.Importance = widget.HighImportance
+	}
+	t.filterBar.Add(all)
+	for _, tag := range tags {
+		btn := widget.NewButton("#"+tag, func() { t.setTagFilter(tag) })
+		if selected[strings.ToLower(tag)] {
+			btn.Importance = widget.HighImportance
+		}
+		t.filterBar.Add(btn)
+	}
+}
+
+// setTagFilter 切换标签的筛选状态，tag 为空时清除筛选
+func (t *TodoList) setTagFilter(tag string) {
+	t.mu.Lock()
+	if tag == "" {
+		t.tagFilter = make(map[string]bool)
+	} else if key := strings.ToLower(tag); t.tagFilter[key] {
+		delete(t.tagFilter, key)
+	} else {
+		t.tagFilter[key] = true
+	}
+	t.mu.Unlock()
+	t.refreshAllLists()
+}
+
+// sortTasks 按优先级或截止时间排列任务，没有截止时间的任务排在最后
+func sortTasks(tasks []*models.Task, sortBy string) {
+	byPriority := func(a, b *models.Task) int { return b.Priority - a.Priority }
+	byDue := func(a, b *models.Task) int {
+		switch {
+		case a.DueAt == nil && b.DueAt == nil:
+			return 0
+		case a.DueAt == nil:
+			return 1
+		case b.DueAt == nil:
+			return -1
+		}
+		return a.DueAt.Compare(*b.DueAt)
+	}
+
+	first, second := byPriority, byDue
+	switch sortBy {
+	case sortByDue:
+		first, second = byDue, byPriority
+	case sortByManual:
+		first = func(a, b *models.Task) int { return a.Position - b.Position }
+		second = byPriority
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if c := first(tasks[i], tasks[j]); c != 0 {
+			return c < 0
+		}
+		return second(tasks[i], tasks[j]) < 0
+	})
+}
+
 // 修改刷新方法
 func (t *TodoList) refreshAllLists() {
-	t.todoList.list.Refresh()
-	t.doingList.list.Refresh()
-	t.doneList.list.Refresh()
-	t.undoList.list.Refresh()
+	t.refreshMu.Lock()
+	defer t.refreshMu.Unlock()
+
+	t.refreshTagFilter()
+	for _, sl := range t.statusLists() {
+		t.updateItemHeights(sl)
+		sl.list.Refresh()
+	}
 
 	// 更新所有数量显示
-	t.todoList.countLabel.SetText(fmt.Sprintf("%d", len(t.getTasksByStatus(StatusTodo))))
-	t.doingList.countLabel.SetText(fmt.Sprintf("%d", len(t.getTasksByStatus(StatusDoing))))
-	t.doneList.countLabel.SetText(fmt.Sprintf("%d", len(t.getTasksByStatus(StatusDone))))
-	t.undoList.countLabel.SetText(fmt.Sprintf("%d", len(t.getTasksByStatus(StatusUndo))))
+	t.todoList.countLabel.SetText(fmt.Sprintf("%d", len(t.getTasksByStatus(models.StatusTodo))))
+	t.doingList.countLabel.SetText(fmt.Sprintf("%d", len(t.getTasksByStatus(models.StatusDoing))))
+	t.doneList.countLabel.SetText(fmt.Sprintf("%d", len(t.getTasksByStatus(models.StatusDone))))
+	t.cancelledList.countLabel.SetText(fmt.Sprintf("%d", len(t.getTasksByStatus(models.StatusCancelled))))
+}
+
+// statusLists 按看板顺序返回各状态的列表
+func (t *TodoList) statusLists() []*StatusList {
+	return []*StatusList{t.todoList, t.doingList, t.doneList, t.cancelledList}
+}
+
+// updateItemHeights 设置每行的高度并记录下来供拖放时使用，展开子任务的行会更高，调用时必须持有 refreshMu
+func (t *TodoList) updateItemHeights(sl *StatusList) {
+	sl.heights = t.itemHeights(t.getTasksByStatus(sl.status))
+	for i, height := range sl.heights {
+		sl.list.SetItemHeight(i, height)
+	}
+}
+
+// itemHeights 按模板任务项计算每行的高度，不必为每个任务创建任务项。
+// 任务行的高度都相同，展开的行再加上子任务行和添加按钮的高度
+func (t *TodoList) itemHeights(tasks []*models.Task) []float32 {
+	template := NewTodoItem(&models.Task{}, t)
+	rowHeight := template.container.MinSize().Height
+	subtaskHeight := template.newSubtaskRow(&models.Task{}).MinSize().Height
+	addHeight := template.newAddSubtaskRow().MinSize().Height
+
+	heights := make([]float32, len(tasks))
+	for i, task := range tasks {
+		heights[i] = rowHeight
+		if !t.isExpanded(task.ID) {
+			continue
+		}
+		if n := len(t.subtasks(task.ID)); n > 0 {
+			heights[i] += float32(n)*(subtaskHeight+theme.Padding()) + addHeight + theme.Padding()
+		}
+	}
+	return heights
+}
+
+// applyTheme 根据当前主题更新列背景并重建任务项
+func (t *TodoList) applyTheme() {
+	for background, name := range t.columnBackgrounds {
+		background.FillColor = theme.Color(name)
+		background.Refresh()
+	}
+	t.refreshAllLists()
 }
 
 // 添加移除任务的方法
 func (t *TodoList) removeTask(task *models.Task) {
-	// 从内存中移除任务
+	// 从内存中移除任务及其子任务
+	t.mu.Lock()
 	if tasks, ok := t.tasks[t.currentDate]; ok {
-		for i, currentTask := range tasks {
-			if currentTask.ID == task.ID {
-				t.tasks[t.currentDate] = append(tasks[:i], tasks[i+1:]...)
-				break
+		remaining := tasks[:0]
+		for _, currentTask := range tasks {
+			if currentTask.ID == task.ID || (currentTask.ParentID != nil && *currentTask.ParentID == task.ID) {
+				continue
 			}
+			remaining = append(remaining, currentTask)
 		}
+		t.tasks[t.currentDate] = remaining
 	}
+	delete(t.expanded, task.ID)
+	t.mu.Unlock()
 	// 刷新显示
 	t.refreshAllLists()
 }
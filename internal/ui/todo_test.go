@@ -0,0 +1,187 @@
+package ui
+
+import (
+	"TodoList/internal/config"
+	"TodoList/internal/models"
+	"TodoList/internal/storage"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+)
+
+// newTestTodoList 创建使用临时数据库的看板
+func newTestTodoList(t *testing.T) *TodoList {
+	t.Helper()
+	test.NewApp()
+
+	db, err := storage.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewTodoList(db, config.DefaultConfig().Tasks)
+}
+
+func TestTodoListConcurrentRefresh(t *testing.T) {
+	todo := newTestTodoList(t)
+	today := time.Now().Format("2006-01-02")
+	for _, text := range []string{"写周报 #工作", "买菜 #生活", "读书"} {
+		if err := todo.createTask(text, today); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const rounds = 30
+	var wg sync.WaitGroup
+	run := func(fn func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				fn(i)
+			}
+		}()
+	}
+
+	// 界面事件：排序、标签筛选
+	run(func(i int) {
+		todo.onSortSelected([]string{sortByPriority, sortByDue, sortByManual}[i%3])
+		todo.setTagFilter([]string{"工作", "", "生活"}[i%3])
+	})
+	// 配置重新加载和本地接口触发的刷新
+	run(func(i int) {
+		mode := config.RolloverMove
+		if i%2 == 0 {
+			mode = config.RolloverCopy
+		}
+		runOnUI(func() { todo.setRolloverMode(mode) })
+		runOnUI(func() {
+			if err := todo.reload(); err != nil {
+				t.Error(err)
+			}
+		})
+	})
+	// 计时器写入工作时段后的刷新
+	run(func(i int) {
+		runOnUI(todo.refreshPomodoroCounts)
+	})
+	wg.Wait()
+	flushUI()
+
+	// 清除筛选后显示全部任务，筛选栏包含标签文字、全部和两个标签
+	todo.setTagFilter("")
+	if got := len(todo.getTasksByStatus(models.StatusTodo)); got != 3 {
+		t.Fatalf("todo tasks = %d, want 3", got)
+	}
+	if got := len(todo.filterBar.Objects); got != 4 {
+		t.Fatalf("filter bar items = %d, want 4", got)
+	}
+}
+
+// taskByTitle 返回看板当前日期中标题为 title 的任务
+func taskByTitle(t *testing.T, todo *TodoList, title string) *models.Task {
+	t.Helper()
+	todo.mu.RLock()
+	defer todo.mu.RUnlock()
+	for _, task := range todo.tasks[todo.currentDate] {
+		if task.Title == title {
+			return task
+		}
+	}
+	t.Fatalf("no task %q", title)
+	return nil
+}
+
+// manualOrder 按数据库中保存的手动顺序返回 status 列的任务标题
+func manualOrder(t *testing.T, todo *TodoList, status models.TaskStatus) []string {
+	t.Helper()
+	tasks, err := todo.db.GetTasksByDate(todo.date())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var column []*models.Task
+	for _, task := range tasks {
+		if task.Status == status && task.ParentID == nil {
+			column = append(column, task)
+		}
+	}
+	sortTasks(column, sortByManual)
+	titles := make([]string, len(column))
+	for i, task := range column {
+		titles[i] = task.Title
+	}
+	return titles
+}
+
+func TestPlaceTaskKeepsManualOrderUnderPrioritySort(t *testing.T) {
+	todo := newTestTodoList(t)
+	today := todo.date()
+	for _, title := range []string{"A", "B", "C", "D"} {
+		if err := todo.createTask(title, today); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 手动排序时把 A、B、C 依次放到 Doing 列
+	todo.onSortSelected(sortByManual)
+	for i, title := range []string{"A", "B", "C"} {
+		if err := todo.placeTask(taskByTitle(t, todo, title), models.StatusDoing, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for title, priority := range map[string]int{"A": models.PriorityLow, "B": models.PriorityHigh, "C": models.PriorityMedium} {
+		task := taskByTitle(t, todo, title)
+		task.Priority = priority
+		if err := todo.db.UpdateTask(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 按优先级显示为 B、C、A，把 D 拖到 C 前面
+	todo.onSortSelected(sortByPriority)
+	if err := todo.placeTask(taskByTitle(t, todo, "D"), models.StatusDoing, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := manualOrder(t, todo, models.StatusDoing), []string{"A", "B", "D", "C"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("manual order = %v, want %v", got, want)
+	}
+	if todo.sortBy != sortByPriority {
+		t.Fatalf("sort = %q, want to keep sorting by priority", todo.sortBy)
+	}
+}
+
+func TestItemHeightsMatchItems(t *testing.T) {
+	todo := newTestTodoList(t)
+	today := todo.date()
+	for _, text := range []string{"写周报 #工作 #重要", "读书", "买菜"} {
+		if err := todo.createTask(text, today); err != nil {
+			t.Fatal(err)
+		}
+	}
+	parent := taskByTitle(t, todo, "写周报")
+	for _, title := range []string{"整理数据", "画图"} {
+		if err := todo.createSubtask(parent, title); err != nil {
+			t.Fatal(err)
+		}
+	}
+	due := time.Now().Add(time.Hour)
+	reading := taskByTitle(t, todo, "读书")
+	reading.DueAt = &due
+	reading.RolloverCount = 2
+	todo.mu.Lock()
+	todo.pomodoroCounts = map[int64]int{reading.ID: 3}
+	todo.mu.Unlock()
+
+	tasks := todo.getTasksByStatus(models.StatusTodo)
+	heights := todo.itemHeights(tasks)
+	for i, task := range tasks {
+		if want := NewTodoItem(task, todo).container.MinSize().Height; heights[i] != want {
+			t.Errorf("%s height = %v, want %v", task.Title, heights[i], want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package ui
+
+import (
+	"TodoList/assets"
+	"TodoList/internal/models"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+)
+
+// trayMenu 系统托盘菜单，列出番茄钟页所选日期的番茄钟并提供快捷操作
+type trayMenu struct {
+	window *MainWindow
+	menu   *fyne.Menu
+	labels []string // 上一次显示的菜单文字，没有变化时不重建菜单
+}
+
+// newTrayMenu 创建托盘图标和菜单，并每秒刷新一次倒计时
+func newTrayMenu(w *MainWindow, desk desktop.App) *trayMenu {
+	tray := &trayMenu{
+		window: w,
+		menu:   fyne.NewMenu("番茄钟"),
+	}
+	tray.update()
+
+	// 需要先设置菜单才能设置图标
+	desk.SetSystemTrayMenu(tray.menu)
+	desk.SetSystemTrayIcon(fyne.NewStaticResource("tomato.png", assets.TrayIcon))
+
+	go func() {
+		for range time.Tick(time.Second) {
+			runOnUI(tray.update)
+		}
+	}()
+	return tray
+}
+
+// update 按计时器的当前状态重建菜单项
+func (tray *trayMenu) update() {
+	tm := tray.window.timerManager
+	timers := tm.snapshot()
+	labels := make([]string, len(timers))
+	for i, t := range timers {
+		labels[i] = trayLabel(t)
+	}
+	if len(timers) == 0 {
+		labels = append(labels, emptyTrayLabel(tm.date()))
+	}
+	if tray.labels != nil && slices.Equal(labels, tray.labels) {
+		return
+	}
+	tray.labels = labels
+
+	items := make([]*fyne.MenuItem, 0, len(timers)+3)
+	for i, t := range timers {
+		items = append(items, tray.timerItem(t, labels[i]))
+	}
+	if len(timers) == 0 {
+		empty := fyne.NewMenuItem(labels[0], nil)
+		empty.Disabled = true
+		items = append(items, empty)
+	}
+
+	items = append(items,
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("添加快速任务", tray.window.showQuickTask),
+		fyne.NewMenuItem("显示窗口", tray.window.showWindow),
+	)
+	tray.menu.Items = items
+	tray.menu.Refresh()
+}
+
+// timerItem 计时器的菜单项，子菜单中可以开始、暂停或跳过当前阶段
+func (tray *trayMenu) timerItem(t *PomodoroTimer, label string) *fyne.MenuItem {
+	toggle := fyne.NewMenuItem("开始", t.Start)
+	if t.IsRunning() {
+		toggle = fyne.NewMenuItem("暂停", t.Stop)
+	}
+
+	item := fyne.NewMenuItem(label, nil)
+	item.ChildMenu = fyne.NewMenu("",
+		toggle,
+		fyne.NewMenuItem("跳过", t.Toggle),
+	)
+	return item
+}
+
+// emptyTrayLabel 所选日期没有番茄钟时菜单中的提示
+func emptyTrayLabel(date time.Time) string {
+	if date.Format("2006-01-02") == time.Now().Format("2006-01-02") {
+		return "今天还没有番茄钟"
+	}
+	return date.Format("2006-01-02") + " 还没有番茄钟"
+}
+
+// trayLabel 菜单中显示的计时器名称、阶段和剩余时间
+func trayLabel(t *PomodoroTimer) string {
+	label := fmt.Sprintf("%s  %s %s", t.Name(), phaseNames[t.engine.Phase()], formatDuration(t.GetRemainingTime()))
+	switch t.engine.State() {
+	case models.StatePaused:
+		label += " (已暂停)"
+	case models.StateIdle:
+		label += " (未开始)"
+	}
+	return label
+}
+
+// showWindow 从托盘重新显示主窗口
+func (w *MainWindow) showWindow() {
+	w.window.Show()
+	w.window.RequestFocus()
+}
+
+// showQuickTask 显示主窗口并弹出添加今天任务的对话框
+func (w *MainWindow) showQuickTask() {
+	w.showWindow()
+
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder("任务标题")
+	items := []*widget.FormItem{widget.NewFormItem("标题", entry)}
+
+	dialog.ShowForm("添加快速任务", "添加", "取消", items, func(ok bool) {
+		title := strings.TrimSpace(entry.Text)
+		if !ok || title == "" {
+			return
+		}
+		if err := w.todo.createTask(title, time.Now().Format("2006-01-02")); err != nil {
+			dialog.ShowError(fmt.Errorf("添加任务失败: %v", err), w.window)
+		}
+	}, w.window)
+}
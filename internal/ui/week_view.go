@@ -0,0 +1,238 @@
+package ui
+
+import (
+	"TodoList/internal/models"
+	"TodoList/internal/storage"
+	"fmt"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// WeekView 按周显示任务，每天一列，最后一列是尚未安排日期的任务，拖动任务到其他列可以修改日期
+type WeekView struct {
+	container  *fyne.Container
+	db         *storage.Database
+	start      time.Time // 显示的一周的周一
+	rangeLabel *widget.Label
+	columns    []*weekColumn // 周一到周日，最后是待安排
+	input      *widget.Entry
+	window     fyne.Window // 显示对话框的窗口
+	onChanged  func()      // 修改任务后的回调，用于刷新看板
+
+	// 界面事件和 runOnUI 都会刷新周视图，保护 start 和各列的内容
+	mu sync.Mutex
+}
+
+// weekColumn 周视图中的一列
+type weekColumn struct {
+	date       string // 为空表示待安排
+	title      *widget.Label
+	tasks      *fyne.Container
+	background *canvas.Rectangle
+	area       fyne.CanvasObject // 拖放任务的目标区域
+}
+
+func NewWeekView(db *storage.Database) *WeekView {
+	wv := &WeekView{
+		db:         db,
+		start:      weekStart(todayDate()),
+		rangeLabel: widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{Bold: true}),
+		input:      widget.NewEntry(),
+	}
+	wv.setup()
+	wv.refresh()
+	return wv
+}
+
+func (wv *WeekView) setup() {
+	toolbar := container.NewHBox(
+		widget.NewButtonWithIcon("", theme.NavigateBackIcon(), func() { wv.shiftWeek(-1) }),
+		wv.rangeLabel,
+		widget.NewButtonWithIcon("", theme.NavigateNextIcon(), func() { wv.shiftWeek(1) }),
+		widget.NewButton("本周", func() { wv.showWeek(todayDate()) }),
+	)
+
+	grid := container.NewGridWithColumns(8)
+	for i := 0; i < 8; i++ {
+		col := &weekColumn{
+			title:      widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{}),
+			tasks:      container.NewVBox(),
+			background: canvas.NewRectangle(theme.Color(theme.ColorNameInputBackground)),
+		}
+		col.area = container.NewStack(
+			col.background,
+			container.NewBorder(col.title, nil, nil, nil, container.NewVScroll(col.tasks)),
+		)
+		wv.columns = append(wv.columns, col)
+		grid.Add(col.area)
+	}
+	wv.columns[7].title.SetText("待安排")
+
+	// 输入框添加的任务放到待安排中
+	wv.input.SetPlaceHolder("添加待安排的任务... 可以使用 #标签")
+	wv.input.OnSubmitted = func(string) { wv.addBacklogTask() }
+	inputContainer := container.NewBorder(
+		nil, nil, nil, widget.NewButtonWithIcon("Add Task", theme.ContentAddIcon(), wv.addBacklogTask),
+		wv.input,
+	)
+
+	wv.container = container.NewBorder(
+		container.NewVBox(toolbar, inputContainer),
+		nil, nil, nil,
+		grid,
+	)
+}
+
+// showWeek 显示 date 所在的一周
+func (wv *WeekView) showWeek(date time.Time) {
+	wv.mu.Lock()
+	wv.start = weekStart(date)
+	wv.mu.Unlock()
+	wv.refresh()
+}
+
+// shiftWeek 显示前后第 weeks 周
+func (wv *WeekView) shiftWeek(weeks int) {
+	wv.mu.Lock()
+	date := wv.start.AddDate(0, 0, 7*weeks)
+	wv.mu.Unlock()
+	wv.showWeek(date)
+}
+
+// refresh 重新加载这一周和待安排的任务
+func (wv *WeekView) refresh() {
+	wv.mu.Lock()
+	defer wv.mu.Unlock()
+
+	end := wv.start.AddDate(0, 0, 6)
+	wv.rangeLabel.SetText(fmt.Sprintf("%s ~ %s", wv.start.Format("2006-01-02"), end.Format("2006-01-02")))
+
+	// 先生成重复任务在这一周的实例
+	for day := wv.start; !day.After(end); day = day.AddDate(0, 0, 1) {
+		if err := wv.db.MaterializeRecurrences(day.Format("2006-01-02")); err != nil {
+			fmt.Println("Error materializing recurrences:", err)
+		}
+	}
+
+	tasks, err := wv.db.GetTasksInRange(wv.start.Format("2006-01-02"), end.Format("2006-01-02"))
+	if err != nil {
+		fmt.Println("Error loading tasks:", err)
+		return
+	}
+	backlog, err := wv.db.GetBacklogTasks()
+	if err != nil {
+		fmt.Println("Error loading backlog:", err)
+		return
+	}
+
+	byDate := make(map[string][]*models.Task)
+	for _, task := range append(tasks, backlog...) {
+		// 子任务跟随父任务，不单独显示
+		if task.ParentID == nil {
+			byDate[task.Date] = append(byDate[task.Date], task)
+		}
+	}
+
+	today := todayDate()
+	for i, col := range wv.columns {
+		if i < 7 {
+			day := wv.start.AddDate(0, 0, i)
+			col.date = day.Format("2006-01-02")
+			col.title.SetText(fmt.Sprintf("周%s %s", models.WeekdayName(day.Weekday()), day.Format("01-02")))
+			col.title.TextStyle = fyne.TextStyle{Bold: day.Equal(today)}
+			col.title.Refresh()
+		}
+		col.background.FillColor = theme.Color(theme.ColorNameInputBackground)
+		col.background.Refresh()
+
+		col.tasks.RemoveAll()
+		for _, task := range byDate[col.date] {
+			col.tasks.Add(wv.newTaskCard(task))
+		}
+		col.tasks.Refresh()
+	}
+}
+
+// newTaskCard 创建可以拖动的任务卡片，已完成和已取消的任务使用灰色文字
+func (wv *WeekView) newTaskCard(task *models.Task) fyne.CanvasObject {
+	title := canvas.NewText(task.Title, theme.Color(theme.ColorNameForeground))
+	if task.Status == models.StatusDone || task.Status == models.StatusCancelled {
+		title.Color = theme.Color(theme.ColorNamePlaceHolder)
+		title.TextStyle = fyne.TextStyle{Italic: true}
+	}
+
+	row := container.NewHBox(newPriorityBadge(task.Priority), title)
+	if task.RecurrenceID != nil {
+		row.Add(widget.NewIcon(theme.ViewRefreshIcon()))
+	}
+	card := container.NewStack(
+		canvas.NewRectangle(theme.Color(theme.ColorNameBackground)),
+		container.New(layout.NewCustomPaddedLayout(2, 2, 4, 4), row),
+	)
+	return newDraggable(card, task.Title, func(pos fyne.Position) {
+		wv.onTaskDropped(task, pos)
+	})
+}
+
+// onTaskDropped 将任务移动到松开鼠标时所在的列
+func (wv *WeekView) onTaskDropped(task *models.Task, pos fyne.Position) {
+	for _, col := range wv.columns {
+		if !containsPoint(col.area, pos) {
+			continue
+		}
+		wv.mu.Lock()
+		date := col.date
+		wv.mu.Unlock()
+		if date == task.Date {
+			return
+		}
+
+		previous := task.Date
+		task.Date = date
+		if err := wv.db.UpdateTask(task); err != nil {
+			task.Date = previous
+			dialog.ShowError(fmt.Errorf("移动任务失败: %v", err), wv.window)
+			return
+		}
+		wv.refresh()
+		if wv.onChanged != nil {
+			wv.onChanged()
+		}
+		return
+	}
+}
+
+// addBacklogTask 添加输入框中的任务到待安排
+func (wv *WeekView) addBacklogTask() {
+	title, tags := models.ParseTags(wv.input.Text)
+	if title == "" {
+		return
+	}
+
+	task := &models.Task{
+		Title:     title,
+		Status:    models.StatusTodo,
+		CreatedAt: time.Now(),
+		Priority:  1,
+		Tags:      tags,
+	}
+	if err := wv.db.SaveTask(task); err != nil {
+		dialog.ShowError(fmt.Errorf("添加任务失败: %v", err), wv.window)
+		return
+	}
+	wv.input.SetText("")
+	wv.refresh()
+}
+
+// weekStart 返回 date 所在周的周一
+func weekStart(date time.Time) time.Time {
+	return date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+}
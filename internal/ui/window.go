@@ -1,54 +1,208 @@
 package ui
 
 import (
+	"TodoList/internal/api"
+	"TodoList/internal/audio"
 	"TodoList/internal/config"
+	"TodoList/internal/models"
 	"TodoList/internal/storage"
+	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/driver/desktop"
 )
 
 type MainWindow struct {
+	app           fyne.App
 	window        fyne.Window
+	tabs          *container.AppTabs
 	timerManager  *TimerManager
 	todo          *TodoList
+	week          *WeekView
+	settings      *SettingsView
 	db            *storage.Database
 	configManager *config.Manager
+	player        *audio.Player
+	tray          *trayMenu // 平台不支持系统托盘时为 nil
 }
 
-func NewMainWindow(app fyne.App, configManager *config.Manager) *MainWindow {
-	db, err := storage.NewDatabase()
-	if err != nil {
-		return nil
-	}
+// NewMainWindow 创建主窗口，提示音通过 sink 播放
+func NewMainWindow(app fyne.App, configManager *config.Manager, db *storage.Database, sink audio.Sink) *MainWindow {
+	cfg := configManager.GetConfig()
+	player := audio.NewPlayer(sink)
+	applySounds(player, configManager, cfg.Pomodoro)
 
+	window := app.NewWindow("番茄钟 + 待办事项")
 	w := &MainWindow{
-		window:        app.NewWindow("番茄钟 + 待办事项"),
+		app:           app,
+		window:        window,
 		configManager: configManager,
 		db:            db,
-		timerManager:  NewTimerManager(db),
-		todo:          NewTodoList(db),
+		player:        player,
+		timerManager:  NewTimerManager(db, cfg.Pomodoro, player, &appNotifier{app: app}, window),
+		todo:          NewTodoList(db, cfg.Tasks),
 	}
 	w.setup()
+
+	// 配置文件修改后即时生效
+	configManager.WatchConfig(w.onConfigChanged)
+	configManager.SetOnError(func(err error) {
+		dialog.ShowError(err, w.window)
+	})
 	return w
 }
 
+// onConfigChanged 应用重新加载的配置，在配置监听协程中调用
+func (w *MainWindow) onConfigChanged(cfg *config.Config) {
+	applySounds(w.player, w.configManager, cfg.Pomodoro)
+	w.db.SetAutoCompleteParent(cfg.Tasks.AutoCompleteParent)
+	runOnUI(func() {
+		ApplyTheme(w.app, cfg.Theme)
+		w.settings.load(cfg.Theme)
+		w.timerManager.ApplyPomodoroConfig(cfg.Pomodoro)
+		w.todo.setRolloverMode(cfg.Tasks.RolloverMode)
+	})
+}
+
 func (w *MainWindow) SetSize(width, height float32) {
 	w.window.Resize(fyne.NewSize(width, height))
 }
 
 func (w *MainWindow) setup() {
 	stats := NewStatsView(w.db)
+	w.week = NewWeekView(w.db)
+	w.settings = NewSettingsView(w.configManager, w.window)
 
-	tabs := container.NewAppTabs(
+	w.tabs = container.NewAppTabs(
 		container.NewTabItem("番茄钟", w.timerManager.container),
 		container.NewTabItem("待办事项", w.todo.container),
+		container.NewTabItem("周视图", w.week.container),
 		container.NewTabItem("统计", stats.container),
+		container.NewTabItem("设置", w.settings.container),
 	)
 
-	w.window.SetContent(tabs)
+	// 主题变化后刷新使用自定义颜色的组件
+	settingsChanged := make(chan fyne.Settings)
+	w.app.Settings().AddChangeListener(settingsChanged)
+	go func() {
+		for range settingsChanged {
+			runOnUI(func() {
+				w.timerManager.applyTheme()
+				w.todo.applyTheme()
+				w.week.refresh()
+			})
+		}
+	}()
+
+	// 在周视图中修改日期后刷新看板，切换到周视图时重新加载看板上的修改
+	w.week.window = w.window
+	w.week.onChanged = func() {
+		if err := w.todo.reload(); err != nil {
+			fmt.Println("Error loading tasks:", err)
+		}
+	}
+	w.tabs.OnSelected = func(tab *container.TabItem) {
+		if tab.Content == w.week.container {
+			w.week.refresh()
+		}
+	}
+
+	// 在看板上选择专注任务后切换到番茄钟页
+	w.todo.window = w.window
+	w.todo.SetOnFocusTask(func(task *models.Task) {
+		w.tabs.SelectIndex(0)
+		w.timerManager.FocusTask(task)
+	})
+	w.timerManager.SetOnRecordSaved(func(*models.PomodoroRecord) {
+		runOnUI(w.todo.refreshPomodoroCounts)
+	})
+
+	// 支持系统托盘时显示托盘菜单，可以配置关闭窗口时隐藏到托盘
+	if desk, ok := w.app.(desktop.App); ok {
+		w.tray = newTrayMenu(w, desk)
+		w.window.SetCloseIntercept(w.onCloseRequested)
+	}
+
+	w.addDateShortcuts()
+	w.addBoardShortcuts()
+
+	w.window.SetContent(w.tabs)
 	w.window.Resize(fyne.NewSize(400, 500))
 }
 
+// addDateShortcuts 注册切换日期的快捷键，作用于当前页的日期选择器：
+// Alt+← 前一天，Alt+→ 后一天，Alt+Home 今天
+func (w *MainWindow) addDateShortcuts() {
+	shortcuts := map[fyne.KeyName]func(*DatePicker){
+		fyne.KeyLeft:  (*DatePicker).PrevDay,
+		fyne.KeyRight: (*DatePicker).NextDay,
+		fyne.KeyHome:  (*DatePicker).Today,
+	}
+	for key, action := range shortcuts {
+		action := action
+		w.window.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: key, Modifier: fyne.KeyModifierAlt}, func(fyne.Shortcut) {
+			if picker := w.datePicker(); picker != nil {
+				action(picker)
+			}
+		})
+	}
+}
+
+// addBoardShortcuts 注册在看板中移动选中任务的快捷键，可以代替拖动：
+// Ctrl+← 和 Ctrl+→ 移动到左右两列，Ctrl+↑ 和 Ctrl+↓ 在列内上移和下移
+func (w *MainWindow) addBoardShortcuts() {
+	shortcuts := map[fyne.KeyName]func(){
+		fyne.KeyLeft:  func() { w.todo.moveSelected(-1) },
+		fyne.KeyRight: func() { w.todo.moveSelected(1) },
+		fyne.KeyUp:    func() { w.todo.reorderSelected(-1) },
+		fyne.KeyDown:  func() { w.todo.reorderSelected(1) },
+	}
+	for key, action := range shortcuts {
+		action := action
+		w.window.Canvas().AddShortcut(&desktop.CustomShortcut{KeyName: key, Modifier: fyne.KeyModifierShortcutDefault}, func(fyne.Shortcut) {
+			if w.tabs.Selected().Content == w.todo.container {
+				action()
+			}
+		})
+	}
+}
+
+// datePicker 返回当前页的日期选择器，没有日期选择器的页面返回 nil
+func (w *MainWindow) datePicker() *DatePicker {
+	switch w.tabs.Selected().Content {
+	case w.timerManager.container:
+		return w.timerManager.datePicker
+	case w.todo.container:
+		return w.todo.datePicker
+	}
+	return nil
+}
+
+// onCloseRequested 用户关闭窗口时按配置隐藏到托盘或退出
+func (w *MainWindow) onCloseRequested() {
+	if w.configManager.GetConfig().App.MinimizeToTray {
+		w.window.Hide()
+		return
+	}
+	w.window.Close()
+}
+
+// Timers 返回可以通过本地接口控制的番茄钟
+func (w *MainWindow) Timers() api.Timers {
+	return w.timerManager
+}
+
+// ReloadTasks 重新加载当前日期的任务，用于数据在界面之外被修改后刷新，可以在任意协程中调用
+func (w *MainWindow) ReloadTasks() {
+	runOnUI(func() {
+		if err := w.todo.reload(); err != nil {
+			fmt.Println("Error loading tasks:", err)
+		}
+		w.week.refresh()
+	})
+}
+
 func (w *MainWindow) Show() {
 	w.window.ShowAndRun()
 }